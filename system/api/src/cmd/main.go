@@ -4,7 +4,17 @@ import (
 	"fmt"
 	"os"
 	"social_api/db"
+	"social_api/feed"
+	"social_api/lockout"
+	"social_api/mailer"
+	"social_api/middlewares"
+	"social_api/oidc"
+	"social_api/passkeys"
+	"social_api/passwords"
+	"social_api/ratelimit"
 	"social_api/router"
+	"social_api/storage"
+	"social_api/tokens"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/requestid"
@@ -20,7 +30,59 @@ func main() {
 
 	defer db.CloseDB()
 
-	app := fiber.New()
+	if err := feed.Init(); err != nil {
+		fmt.Printf("Error initializing feed: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := storage.Init(); err != nil {
+		fmt.Printf("Error initializing storage: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := tokens.Init(); err != nil {
+		fmt.Printf("Error initializing tokens: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := lockout.Init(); err != nil {
+		fmt.Printf("Error initializing login lockout: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := ratelimit.Init(); err != nil {
+		fmt.Printf("Error initializing rate limits: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := passwords.Init(); err != nil {
+		fmt.Printf("Error initializing password policy: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := mailer.Init(); err != nil {
+		fmt.Printf("Error initializing mailer: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := passkeys.Init(); err != nil {
+		fmt.Printf("Error initializing passkeys: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := oidc.Init(); err != nil {
+		fmt.Printf("Error initializing OIDC providers: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Bodies over the limit are streamed, and multipart forms are only read
+	// by the routes that take files, so that uploads are not held in memory.
+	// middlewares.LimitBody decides how big each route accepts them.
+	app := fiber.New(fiber.Config{
+		BodyLimit:                    middlewares.DefaultBodyLimit,
+		StreamRequestBody:            true,
+		DisablePreParseMultipartForm: true,
+	})
 
 	app.Use(requestid.New())
 
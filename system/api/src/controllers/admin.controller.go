@@ -0,0 +1,131 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"social_api/middlewares"
+	"social_api/models"
+	"social_api/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+)
+
+// ListUsersHandler lists the users, newest first. ?role= only keeps the users
+// with that role, and ?suspended=true only the suspended ones.
+func ListUsersHandler(c *fiber.Ctx) error {
+	page, err := utils.ParsePage(c)
+	if err != nil {
+		utils.HandleError(c, err, http.StatusBadRequest)
+		return nil
+	}
+
+	role := c.Query("role")
+	if role != "" && !models.RoleAtLeast(role, models.RoleUser) {
+		utils.HandleError(c, utils.ErrInvalidRole, http.StatusBadRequest)
+		return nil
+	}
+
+	users, nextCursor, err := utils.ListUsers(role, c.QueryBool("suspended"), page)
+	if err != nil {
+		utils.HandleError(c, utils.ErrFindUsers, http.StatusInternalServerError)
+		return nil
+	}
+
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"users":       users,
+		"next_cursor": nextCursor,
+	})
+}
+
+// SuspendUserHandler keeps a user from logging in and logs them out of every
+// session.
+func SuspendUserHandler(c *fiber.Ctx) error {
+	user := moderatedUser(c)
+	if user == nil {
+		return nil
+	}
+
+	if err := utils.SuspendUser(user.ID.String); err != nil {
+		utils.HandleError(c, utils.ErrModerateUser, http.StatusInternalServerError)
+		return nil
+	}
+
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"message": user.Username + " was suspended.",
+	})
+}
+
+// UnsuspendUserHandler lets a suspended user log in again.
+func UnsuspendUserHandler(c *fiber.Ctx) error {
+	user := moderatedUser(c)
+	if user == nil {
+		return nil
+	}
+
+	if err := utils.UnsuspendUser(user.ID.String); err != nil {
+		utils.HandleError(c, utils.ErrModerateUser, http.StatusInternalServerError)
+		return nil
+	}
+
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"message": user.Username + " is no longer suspended.",
+	})
+}
+
+// ForcePasswordResetHandler logs a user out of every session and keeps them
+// from logging in until they reset their password, with the link emailed to
+// them or a new one from /api/password/forgot.
+func ForcePasswordResetHandler(c *fiber.Ctx) error {
+	user := moderatedUser(c)
+	if user == nil {
+		return nil
+	}
+
+	if err := utils.RequirePasswordReset(user.ID.String); err != nil {
+		utils.HandleError(c, utils.ErrModerateUser, http.StatusInternalServerError)
+		return nil
+	}
+
+	go func() {
+		if err := sendPasswordResetEmail(user.Email); err != nil {
+			fmt.Println("Error sending password reset email:", err)
+		}
+	}()
+
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"message": user.Username + " has to reset their password. A link to do it was sent to their email.",
+	})
+}
+
+// moderatedUser returns the user of the :id parameter, as long as the user
+// that made the request has a higher role. Otherwise it answers with an error
+// and returns nil.
+func moderatedUser(c *fiber.Ctx) *models.User {
+	userID := c.Params("id")
+	if _, err := uuid.Parse(userID); err != nil {
+		utils.HandleError(c, utils.ErrInvalidID, http.StatusBadRequest)
+		return nil
+	}
+
+	user, err := utils.FindUserById(userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			utils.HandleError(c, utils.ErrUserNotFound, http.StatusNotFound)
+			return nil
+		}
+		utils.HandleError(c, utils.ErrFindUser, http.StatusInternalServerError)
+		return nil
+	}
+
+	// Users cannot moderate themselves or their peers either.
+	if !models.RoleAbove(middlewares.AuthClaims(c).Role, user.Role) {
+		utils.HandleError(c, utils.ErrRoleTooHigh, http.StatusForbidden)
+		return nil
+	}
+
+	return user
+}
@@ -4,11 +4,19 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
 	"net/http"
 	"social_api/libs"
+	"social_api/lockout"
+	"social_api/middlewares"
 	"social_api/models"
 	"social_api/schemas"
+	"social_api/tokens"
 	"social_api/utils"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	ut "github.com/go-playground/universal-translator"
 
@@ -42,6 +50,7 @@ func RegisterHandler(c *fiber.Ctx) error {
 		Password:  string(passwordHash),
 		FirstName: firstname,
 		LastName:  lastname,
+		Role:      models.RoleUser,
 	}
 
 	newUserID, errSavingUser := utils.SaveUser(newUser)
@@ -52,20 +61,25 @@ func RegisterHandler(c *fiber.Ctx) error {
 		})
 	}
 
-	token, err := libs.GenerateJWT(newUserID.String)
+	go func() {
+		if err := sendVerificationEmail(newUserID.String, email); err != nil {
+			fmt.Println("Error sending verification email:", err)
+		}
+	}()
+
+	refreshToken, err := startSession(c, newUserID.String, newUser.Role)
 	if err != nil {
 		// Manejar el error
 		utils.HandleError(c, utils.ErrGenerateJWT, http.StatusInternalServerError)
-		c.Status(http.StatusInternalServerError).Send([]byte("Error generating JWT."))
 		return errors.New("Error generando token JWT")
 	}
 
 	response := map[string]interface{}{
-		"message": "Registration successful",
-		"user":    utils.UserWithoutPassword(newUser, newUserID.String),
+		"message":       "Registration successful",
+		"user":          utils.UserWithoutPassword(newUser, newUserID.String),
+		"refresh_token": refreshToken,
 	}
 
-	c.Set("session", token)
 	return c.Status(200).JSON(response)
 }
 
@@ -110,6 +124,17 @@ func LoginHandler(c *fiber.Ctx) error {
 		})
 	}
 
+	// Failures are counted by the username or email sent rather than by
+	// account, so that unknown ones behave like the others.
+	accountKey := "username:" + strings.ToLower(requestBody.Username)
+	if requestBody.Username == "" {
+		accountKey = "email:" + strings.ToLower(requestBody.Email)
+	}
+
+	if wait := tryLogin(c, accountKey); wait > 0 {
+		return tooManyLogins(c, wait)
+	}
+
 	var user *models.User
 	var err error
 
@@ -120,22 +145,23 @@ func LoginHandler(c *fiber.Ctx) error {
 	}
 
 	if err != nil {
+		abortLogin(c, accountKey)
 		utils.HandleError(c, utils.ErrFindUser, http.StatusInternalServerError)
 		return errors.New("Error searching the user.")
 	}
 
-	if user == nil {
-		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{
-			"error": "User not found.",
-		})
-	} else {
-		err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(requestBody.Password))
-		if err != nil {
-			return c.Status(http.StatusUnauthorized).JSON(fiber.Map{
-				"error": "Wrong password",
-			})
-		}
+	passwordHash := dummyPasswordHash()
+	if user != nil {
+		passwordHash = []byte(user.Password)
+	}
+	// The password is compared even without a user, so the response takes
+	// as long and unknown users cannot be told apart.
+	if err := bcrypt.CompareHashAndPassword(passwordHash, []byte(requestBody.Password)); err != nil || user == nil {
+		return failLogin(c)
 	}
+
+	succeedLogin(c, accountKey)
+
 	var userID string
 	if user.ID.Valid {
 		userID = user.ID.String
@@ -143,25 +169,241 @@ func LoginHandler(c *fiber.Ctx) error {
 		userID = ""
 	}
 
-	token, err := libs.GenerateJWT(userID)
+	return continueLogin(c, *user, userID)
+}
+
+// tryLogin starts a login attempt of the account of accountKey and of the IP
+// of the request, which counts as failed until succeedLogin. When either has
+// to wait, it returns how long and the attempt is not counted.
+func tryLogin(c *fiber.Ctx, accountKey string) time.Duration {
+	if wait := lockout.IPs.Try(c.IP()); wait > 0 {
+		if accountWait := lockout.Accounts.Wait(accountKey); accountWait > wait {
+			return accountWait
+		}
+		return wait
+	}
+
+	if wait := lockout.Accounts.Try(accountKey); wait > 0 {
+		lockout.IPs.Release(c.IP())
+		return wait
+	}
+
+	return 0
+}
+
+// succeedLogin forgets the failed logins of the account of accountKey and
+// takes back the attempt of the IP of the request, whose other failures are
+// kept since many users can share it.
+func succeedLogin(c *fiber.Ctx, accountKey string) {
+	lockout.Accounts.Reset(accountKey)
+	lockout.IPs.Release(c.IP())
+}
+
+// abortLogin takes back the attempt of the account of accountKey and of the
+// IP of the request when the login could not be checked, so that errors of
+// the server do not count against users.
+func abortLogin(c *fiber.Ctx, accountKey string) {
+	lockout.Accounts.Release(accountKey)
+	lockout.IPs.Release(c.IP())
+}
+
+// failLogin refuses a login attempt, which tryLogin already counted. Whatever
+// was wrong, the response is the same.
+func failLogin(c *fiber.Ctx) error {
+	utils.HandleError(c, utils.ErrInvalidCredentials, http.StatusUnauthorized)
+	return nil
+}
+
+// tooManyLogins refuses a login attempt that came before the end of wait.
+func tooManyLogins(c *fiber.Ctx, wait time.Duration) error {
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	utils.HandleError(c, utils.ErrTooManyLogins, http.StatusTooManyRequests)
+	return nil
+}
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     []byte
+)
+
+// dummyPasswordHash is a hash to compare passwords against when there is no
+// user, made once.
+func dummyPasswordHash() []byte {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+	})
+	return dummyHash
+}
+
+// continueLogin asks for the second factor of user once their first one was
+// checked, or logs them in when they do not have one. With two-factor
+// authentication on, the first factor alone only earns a token to send the
+// code with to /api/login/2fa.
+func continueLogin(c *fiber.Ctx, user models.User, userID string) error {
+	if refuseLogin(c, user) {
+		return nil
+	}
+
+	totp, err := utils.FindTOTP(userID)
+	if err != nil {
+		utils.HandleError(c, utils.ErrFindUser, http.StatusInternalServerError)
+		return errors.New("Error searching the user.")
+	}
+	if totp != nil && totp.Confirmed {
+		mfaToken, err := tokens.GeneratePurposeToken(tokens.PurposeMFA, userID, user.Email, tokens.MFATokenLifetime)
+		if err != nil {
+			utils.HandleError(c, utils.ErrGenerateJWT, http.StatusInternalServerError)
+			return errors.New("Error generating JWT token.")
+		}
+
+		return c.Status(200).JSON(fiber.Map{
+			"message":      "Two-factor authentication required.",
+			"mfa_required": true,
+			"mfa_token":    mfaToken,
+		})
+	}
+
+	return completeLogin(c, user, userID)
+}
+
+// completeLogin opens a session for user once every factor was checked and
+// answers with their profile and refresh token.
+func completeLogin(c *fiber.Ctx, user models.User, userID string) error {
+	if refuseLogin(c, user) {
+		return nil
+	}
+
+	refreshToken, err := startSession(c, userID, user.Role)
 	if err != nil {
 		utils.HandleError(c, utils.ErrGenerateJWT, http.StatusInternalServerError)
-		c.Status(http.StatusInternalServerError).Send([]byte("Error generating JWT token"))
 		return errors.New("Error generating JWT token.")
 	}
 
 	response := map[string]interface{}{
-		"message": "Login successful.",
-		"user":    utils.UserWithoutPassword(*user, userID),
+		"message":       "Login successful.",
+		"user":          utils.UserWithoutPassword(user, userID),
+		"refresh_token": refreshToken,
 	}
 
-	c.Set("session", token)
 	return c.Status(200).JSON(response)
 }
 
+// refuseLogin answers with an error when user cannot log in, because they
+// are suspended or have to reset their password, and reports whether it did.
+// It is only called once their credentials were checked, so that it tells
+// nothing about accounts to others.
+func refuseLogin(c *fiber.Ctx, user models.User) bool {
+	switch {
+	case user.SuspendedAt.Valid:
+		utils.HandleError(c, utils.ErrUserSuspended, http.StatusForbidden)
+		return true
+	case user.PasswordResetRequired:
+		utils.HandleError(c, utils.ErrPasswordResetRequired, http.StatusForbidden)
+		return true
+	}
+	return false
+}
+
+// RefreshTokenHandler trades a refresh token for a new access token and a
+// new refresh token. The old refresh token stops working. The new access
+// token carries the current role of the user.
+func RefreshTokenHandler(c *fiber.Ctx) error {
+	var requestBody schemas.RefreshTokenRequest
+	if err := json.Unmarshal(c.Body(), &requestBody); err != nil {
+		utils.HandleError(c, utils.ErrDecodeRequest, http.StatusBadRequest)
+		return nil
+	}
+
+	newRefreshToken, newRefreshHash, err := libs.GenerateSecretToken()
+	if err != nil {
+		utils.HandleError(c, utils.ErrRefreshToken, http.StatusInternalServerError)
+		return nil
+	}
+
+	userID, sessionID, role, err := utils.RotateRefreshToken(libs.HashSecretToken(requestBody.RefreshToken), newRefreshHash, libs.RefreshTokenLifetime)
+	if err != nil {
+		switch {
+		case errors.Is(err, utils.ErrInvalidRefreshToken), errors.Is(err, utils.ErrRefreshTokenReused), errors.Is(err, utils.ErrRefreshTokenExpired):
+			utils.HandleError(c, err, http.StatusUnauthorized)
+		default:
+			utils.HandleError(c, utils.ErrRefreshToken, http.StatusInternalServerError)
+		}
+		return nil
+	}
+
+	token, err := tokens.GenerateAccessToken(userID, sessionID, role)
+	if err != nil {
+		utils.HandleError(c, utils.ErrGenerateJWT, http.StatusInternalServerError)
+		return nil
+	}
+
+	c.Set("session", token)
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"refresh_token": newRefreshToken,
+	})
+}
+
+// startSession opens a session for a new login, sets its access token in the
+// session header and returns the refresh token that starts its family.
+func startSession(c *fiber.Ctx, userID, role string) (string, error) {
+	now := time.Now().UTC()
+	requestID, _ := c.Locals("requestid").(string)
+
+	sessionID, err := utils.CreateSession(models.Session{
+		UserID:    userID,
+		UserAgent: c.Get(fiber.HeaderUserAgent),
+		IP:        c.IP(),
+		RequestID: requestID,
+		ExpiresAt: now.Add(libs.SessionLifetime),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	token, err := tokens.GenerateAccessToken(userID, sessionID, role)
+	if err != nil {
+		return "", err
+	}
+
+	refreshToken, refreshHash, err := libs.GenerateSecretToken()
+	if err != nil {
+		return "", err
+	}
+
+	if err := utils.CreateRefreshToken(userID, sessionID, refreshHash, now.Add(libs.RefreshTokenLifetime), now.Add(libs.SessionLifetime)); err != nil {
+		return "", err
+	}
+
+	c.Set("session", token)
+	return refreshToken, nil
+}
+
+// LogoutHandler revokes the session the token belongs to, along with every
+// token issued for it.
 func LogoutHandler(c *fiber.Ctx) error {
-	c.ClearCookie("session")
+	claims := middlewares.AuthClaims(c)
+
+	if err := utils.RevokeSession(claims.UserID, claims.SessionID); err != nil {
+		utils.HandleError(c, utils.ErrLogout, http.StatusInternalServerError)
+		return nil
+	}
+
 	return c.Status(http.StatusOK).JSON(fiber.Map{
 		"message": "Logout successful.",
 	})
 }
+
+// LogoutAllHandler revokes every session of the user, logging them out of
+// every device.
+func LogoutAllHandler(c *fiber.Ctx) error {
+	userID := middlewares.AuthUserID(c)
+
+	if err := utils.RevokeUserSessions(userID); err != nil {
+		utils.HandleError(c, utils.ErrLogout, http.StatusInternalServerError)
+		return nil
+	}
+
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"message": "Logged out of every session.",
+	})
+}
@@ -0,0 +1,36 @@
+package controllers
+
+import (
+	"net/http"
+	"social_api/feed"
+	"social_api/middlewares"
+	"social_api/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// FeedHandler returns the home timeline of the session user. The next page is
+// requested by sending back the next_cursor of the previous one as ?cursor=.
+func FeedHandler(c *fiber.Ctx) error {
+	userID := middlewares.AuthUserID(c)
+
+	page, err := utils.ParsePage(c)
+	if err != nil {
+		utils.HandleError(c, err, http.StatusBadRequest)
+		return nil
+	}
+
+	// One extra post tells whether there is a next page.
+	posts, err := feed.Default.Timeline(userID, page.Cursor, page.Limit+1)
+	if err != nil {
+		utils.HandleError(c, utils.ErrFindPost, http.StatusInternalServerError)
+		return nil
+	}
+
+	posts, nextCursor := utils.TrimPage(posts, page.Limit, utils.PostCursor)
+
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"posts":       posts,
+		"next_cursor": nextCursor,
+	})
+}
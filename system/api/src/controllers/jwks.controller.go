@@ -0,0 +1,17 @@
+package controllers
+
+import (
+	"net/http"
+
+	"social_api/tokens"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// JWKSHandler publishes the public keys access tokens are verified with, so
+// that other services can verify them without any secret. Verifiers pick the
+// key by the kid header of the token.
+func JWKSHandler(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	return c.Status(http.StatusOK).JSON(tokens.Default.Keyring.JWKS())
+}
@@ -0,0 +1,125 @@
+package controllers
+
+import (
+	"net/http"
+	"social_api/middlewares"
+	"social_api/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func LikePostHandler(c *fiber.Ctx) error {
+	return setPostLike(c, true)
+}
+
+func UnlikePostHandler(c *fiber.Ctx) error {
+	return setPostLike(c, false)
+}
+
+func LikeResponseHandler(c *fiber.Ctx) error {
+	return setResponseLike(c, true)
+}
+
+func UnlikeResponseHandler(c *fiber.Ctx) error {
+	return setResponseLike(c, false)
+}
+
+func GetPostLikesHandler(c *fiber.Ctx) error {
+	post, ok := findPostFromParams(c)
+	if !ok {
+		return nil
+	}
+
+	page, err := utils.ParsePage(c)
+	if err != nil {
+		utils.HandleError(c, err, http.StatusBadRequest)
+		return nil
+	}
+
+	likers, nextCursor, err := utils.GetPostLikers(post.ID, page)
+	if err != nil {
+		utils.HandleError(c, utils.ErrFindLikers, http.StatusInternalServerError)
+		return nil
+	}
+
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"likes":       likers,
+		"next_cursor": nextCursor,
+	})
+}
+
+func GetResponseLikesHandler(c *fiber.Ctx) error {
+	response, ok := findResponseFromParams(c)
+	if !ok {
+		return nil
+	}
+
+	page, err := utils.ParsePage(c)
+	if err != nil {
+		utils.HandleError(c, err, http.StatusBadRequest)
+		return nil
+	}
+
+	likers, nextCursor, err := utils.GetResponseLikers(response.ID, page)
+	if err != nil {
+		utils.HandleError(c, utils.ErrFindLikers, http.StatusInternalServerError)
+		return nil
+	}
+
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"likes":       likers,
+		"next_cursor": nextCursor,
+	})
+}
+
+func setPostLike(c *fiber.Ctx, like bool) error {
+	userID := middlewares.AuthUserID(c)
+
+	post, ok := findPostFromParams(c)
+	if !ok {
+		return nil
+	}
+
+	var likes int
+	var err error
+	if like {
+		likes, err = utils.LikePost(post.ID, userID)
+	} else {
+		likes, err = utils.UnlikePost(post.ID, userID)
+	}
+	if err != nil {
+		utils.HandleError(c, utils.ErrLike, http.StatusInternalServerError)
+		return nil
+	}
+
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"liked": like,
+		"likes": likes,
+	})
+}
+
+func setResponseLike(c *fiber.Ctx, like bool) error {
+	userID := middlewares.AuthUserID(c)
+
+	response, ok := findResponseFromParams(c)
+	if !ok {
+		return nil
+	}
+
+	var likes int
+	var err error
+	if like {
+		likes, err = utils.LikeResponse(response.ID, userID)
+	} else {
+		likes, err = utils.UnlikeResponse(response.ID, userID)
+	}
+	if err != nil {
+		utils.HandleError(c, utils.ErrLike, http.StatusInternalServerError)
+		return nil
+	}
+
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"liked": like,
+		"likes": likes,
+	})
+}
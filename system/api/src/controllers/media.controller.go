@@ -0,0 +1,167 @@
+package controllers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"io"
+	"net/http"
+	"social_api/imaging"
+	"social_api/models"
+	"social_api/storage"
+	"social_api/utils"
+	"strings"
+
+	"github.com/gabriel-vasile/mimetype"
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	MaxImageSize = 10 << 20
+	MaxVideoSize = 100 << 20
+	// MaxUploadBodySize and MaxPictureBodySize are the body limits of the
+	// upload routes. They leave room for the multipart framing around the
+	// biggest allowed file.
+	MaxUploadBodySize  = MaxVideoSize + 1<<20
+	MaxPictureBodySize = MaxImageSize + 1<<20
+)
+
+// allowedMediaTypes maps the accepted MIME types to the kind of media.
+var allowedMediaTypes = map[string]string{
+	"image/jpeg":      "image",
+	"image/png":       "image",
+	"image/gif":       "image",
+	"image/webp":      "image",
+	"video/mp4":       "video",
+	"video/webm":      "video",
+	"video/quicktime": "video",
+}
+
+// UploadMediaHandler stores the file sent in the "file" field of a multipart
+// form. The type is detected from the content itself, and the file is stored
+// under the hash of its content, so the returned URL never changes and can be
+// attached to posts and responses. Images are stored as the structured record
+// that posts and responses expect, with one variant per size of
+// imaging.VariantSizes.
+func UploadMediaHandler(c *fiber.Ctx) error {
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		utils.HandleError(c, utils.ErrMissingFile, http.StatusBadRequest)
+		return nil
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		utils.HandleError(c, utils.ErrUploadMedia, http.StatusInternalServerError)
+		return nil
+	}
+	defer file.Close()
+
+	detected, err := mimetype.DetectReader(file)
+	if err != nil {
+		utils.HandleError(c, utils.ErrUploadMedia, http.StatusInternalServerError)
+		return nil
+	}
+
+	contentType := strings.SplitN(detected.String(), ";", 2)[0]
+	kind, ok := allowedMediaTypes[contentType]
+	if !ok {
+		return c.Status(http.StatusUnsupportedMediaType).JSON(fiber.Map{
+			"error": "Unsupported file type " + contentType + ".",
+		})
+	}
+
+	maxSize := int64(MaxImageSize)
+	if kind == "video" {
+		maxSize = MaxVideoSize
+	}
+	if fileHeader.Size > maxSize {
+		utils.HandleError(c, utils.ErrFileTooLarge, http.StatusRequestEntityTooLarge)
+		return nil
+	}
+
+	hash := sha256.New()
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		utils.HandleError(c, utils.ErrUploadMedia, http.StatusInternalServerError)
+		return nil
+	}
+	if _, err := io.Copy(hash, file); err != nil {
+		utils.HandleError(c, utils.ErrUploadMedia, http.StatusInternalServerError)
+		return nil
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		utils.HandleError(c, utils.ErrUploadMedia, http.StatusInternalServerError)
+		return nil
+	}
+
+	contentHash := hex.EncodeToString(hash.Sum(nil))
+
+	if kind == "image" {
+		img, err := imaging.Decode(file)
+		if err != nil {
+			utils.HandleError(c, utils.ErrInvalidImage, http.StatusBadRequest)
+			return nil
+		}
+
+		uploaded, err := storeImage(img, "images/"+contentHash)
+		if err != nil {
+			utils.HandleError(c, utils.ErrUploadMedia, http.StatusInternalServerError)
+			return nil
+		}
+
+		return c.Status(http.StatusCreated).JSON(fiber.Map{
+			"type":  kind,
+			"image": uploaded,
+		})
+	}
+
+	key := kind + "s/" + contentHash + detected.Extension()
+	url, err := storage.Backend.Save(key, file, contentType)
+	if err != nil {
+		utils.HandleError(c, utils.ErrUploadMedia, http.StatusInternalServerError)
+		return nil
+	}
+
+	return c.Status(http.StatusCreated).JSON(fiber.Map{
+		"url":         url,
+		"type":        kind,
+		"contentType": contentType,
+		"size":        fileHeader.Size,
+	})
+}
+
+// storeImage saves every variant of img under keyPrefix and describes them.
+// Encoding from the decoded pixels drops the EXIF data of the original file,
+// GPS position included.
+func storeImage(img image.Image, keyPrefix string) (*models.Image, error) {
+	variants, err := imaging.Variants(img, imaging.VariantSizes)
+	if err != nil {
+		return nil, err
+	}
+
+	stored := models.Image{
+		Blurhash: imaging.Blurhash(img, 4, 3),
+		Variants: make([]models.ImageVariant, 0, len(variants)),
+	}
+
+	for _, variant := range variants {
+		key := fmt.Sprintf("%s/%dx%d%s", keyPrefix, variant.Width, variant.Height, variant.Extension)
+		url, err := storage.Backend.Save(key, bytes.NewReader(variant.Data), variant.ContentType)
+		if err != nil {
+			return nil, err
+		}
+
+		stored.Variants = append(stored.Variants, models.ImageVariant{
+			URL:    url,
+			Width:  variant.Width,
+			Height: variant.Height,
+		})
+	}
+
+	biggest := stored.Variants[len(stored.Variants)-1]
+	stored.URL, stored.Width, stored.Height = biggest.URL, biggest.Width, biggest.Height
+
+	return &stored, nil
+}
@@ -0,0 +1,246 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"social_api/libs"
+	"social_api/models"
+	"social_api/oidc"
+	"social_api/tokens"
+	"social_api/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// oidcLoginCookie keeps the state of a login with a provider while the user
+// is away logging in with it.
+const oidcLoginCookie = "oidc_login"
+
+// OIDCLoginHandler sends the user to log in with the provider of the URL.
+func OIDCLoginHandler(c *fiber.Ctx) error {
+	provider := oidc.Lookup(c.Params("provider"))
+	if provider == nil {
+		utils.HandleError(c, utils.ErrUnknownProvider, http.StatusNotFound)
+		return nil
+	}
+
+	request, err := oidc.NewAuthRequest()
+	if err != nil {
+		utils.HandleError(c, utils.ErrOIDCLogin, http.StatusInternalServerError)
+		return nil
+	}
+
+	token, err := tokens.GenerateOIDCLoginToken(provider.Name, request.State, request.Nonce, request.Verifier)
+	if err != nil {
+		utils.HandleError(c, utils.ErrGenerateJWT, http.StatusInternalServerError)
+		return nil
+	}
+
+	setOIDCLoginCookie(c, token, time.Now().Add(tokens.OIDCLoginLifetime))
+
+	return c.Redirect(provider.AuthCodeURL(request), http.StatusFound)
+}
+
+// OIDCCallbackHandler logs in the user coming back from the provider. The
+// first time, their identity is linked to the account with the same
+// verified email, or to a new account when there is none.
+func OIDCCallbackHandler(c *fiber.Ctx) error {
+	provider := oidc.Lookup(c.Params("provider"))
+	if provider == nil {
+		utils.HandleError(c, utils.ErrUnknownProvider, http.StatusNotFound)
+		return nil
+	}
+
+	claims, err := tokens.ParseOIDCLoginToken(c.Cookies(oidcLoginCookie), provider.Name)
+	setOIDCLoginCookie(c, "", time.Unix(0, 0))
+	if err != nil || subtle.ConstantTimeCompare([]byte(claims.State), []byte(c.Query("state"))) != 1 {
+		utils.HandleError(c, utils.ErrInvalidOIDCState, http.StatusBadRequest)
+		return nil
+	}
+
+	if c.Query("error") != "" || c.Query("code") == "" {
+		utils.HandleError(c, utils.ErrOIDCLogin, http.StatusUnauthorized)
+		return nil
+	}
+
+	request := oidc.AuthRequest{State: claims.State, Nonce: claims.Nonce, Verifier: claims.Verifier}
+	identity, err := provider.Exchange(c.Context(), request, c.Query("code"))
+	if err != nil {
+		utils.HandleError(c, utils.ErrOIDCLogin, http.StatusUnauthorized)
+		return nil
+	}
+
+	user, err := oidcUser(identity)
+	if err != nil {
+		switch {
+		case errors.Is(err, utils.ErrOIDCEmailRequired):
+			utils.HandleError(c, err, http.StatusBadRequest)
+		case errors.Is(err, utils.ErrOIDCAccountExists):
+			utils.HandleError(c, err, http.StatusConflict)
+		default:
+			utils.HandleError(c, utils.ErrOIDCLogin, http.StatusInternalServerError)
+		}
+		return nil
+	}
+
+	return continueLogin(c, *user, user.ID.String)
+}
+
+func setOIDCLoginCookie(c *fiber.Ctx, value string, expires time.Time) {
+	c.Cookie(&fiber.Cookie{
+		Name:     oidcLoginCookie,
+		Value:    value,
+		Path:     "/api/login/oidc",
+		Expires:  expires,
+		Secure:   strings.HasPrefix(appBaseURL(), "https://"),
+		HTTPOnly: true,
+		// Lax, so the cookie comes along when the provider redirects back.
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+}
+
+// oidcUser returns the user identity is linked to, linking it first when it
+// is new. Identities only link to an account when both the provider and the
+// account verified the email, so that nobody takes over an account by
+// registering its email somewhere else first.
+func oidcUser(identity *oidc.Identity) (*models.User, error) {
+	userID, err := utils.UseIdentity(identity.Provider, identity.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if userID != "" {
+		return utils.FindUserById(userID)
+	}
+
+	if identity.Email == "" {
+		return nil, utils.ErrOIDCEmailRequired
+	}
+
+	user, err := utils.FindUserByEmail(identity.Email)
+	if err != nil {
+		return nil, err
+	}
+
+	if user != nil {
+		if !identity.EmailVerified || !user.EmailVerified {
+			return nil, utils.ErrOIDCAccountExists
+		}
+	} else {
+		user, err = createOIDCUser(identity)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := utils.LinkIdentity(user.ID.String, identity.Provider, identity.Subject, identity.Email); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// createOIDCUser registers the user of identity. They get a random password
+// they do not know, and can choose one with the forgot password link.
+func createOIDCUser(identity *oidc.Identity) (*models.User, error) {
+	username, err := oidcUsername(identity)
+	if err != nil {
+		return nil, err
+	}
+
+	password, _, err := libs.GenerateSecretToken()
+	if err != nil {
+		return nil, err
+	}
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	firstName, lastName := identity.GivenName, identity.FamilyName
+	if firstName == "" && lastName == "" {
+		firstName, lastName, _ = strings.Cut(strings.TrimSpace(identity.Name), " ")
+	}
+
+	newUser := models.User{
+		Username:  username,
+		Email:     identity.Email,
+		Password:  string(passwordHash),
+		FirstName: truncate(firstName, 18),
+		LastName:  truncate(strings.TrimSpace(lastName), 50),
+		Role:      models.RoleUser,
+	}
+
+	newUserID, err := utils.SaveUser(newUser)
+	if err != nil {
+		return nil, err
+	}
+	newUser.ID = newUserID
+
+	if identity.EmailVerified {
+		if _, err := utils.VerifyEmail(newUserID.String, identity.Email); err != nil {
+			return nil, err
+		}
+		newUser.EmailVerified = true
+	} else {
+		go func() {
+			if err := sendVerificationEmail(newUserID.String, identity.Email); err != nil {
+				fmt.Println("Error sending verification email:", err)
+			}
+		}()
+	}
+
+	return &newUser, nil
+}
+
+var usernameUnwanted = regexp.MustCompile(`[^a-z0-9_]+`)
+
+// oidcUsername picks a free username for identity, from the username it has
+// at the provider or else its email. A random number is added when it is
+// taken.
+func oidcUsername(identity *oidc.Identity) (string, error) {
+	base := identity.Username
+	if base == "" {
+		base, _, _ = strings.Cut(identity.Email, "@")
+	}
+	base = truncate(usernameUnwanted.ReplaceAllString(strings.ToLower(base), ""), 14)
+	if len(base) < 3 {
+		base = "user"
+	}
+
+	username := base
+	for attempt := 0; attempt < 5; attempt++ {
+		user, err := utils.FindUserByEmailOrUsername("", username)
+		if err != nil {
+			return "", err
+		}
+		if user == nil {
+			return username, nil
+		}
+
+		n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+		if err != nil {
+			return "", err
+		}
+		username = fmt.Sprintf("%s%06d", base, n)
+	}
+
+	return "", errors.New("no free username found")
+}
+
+// truncate cuts s to at most n characters.
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) > n {
+		return string(runes[:n])
+	}
+	return s
+}
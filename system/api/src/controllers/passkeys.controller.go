@@ -0,0 +1,241 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"social_api/middlewares"
+	"social_api/passkeys"
+	"social_api/schemas"
+	"social_api/utils"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+)
+
+// BeginPasskeyRegistrationHandler returns the options to pass to
+// navigator.credentials.create to add a passkey to the account.
+func BeginPasskeyRegistrationHandler(c *fiber.Ctx) error {
+	userID := middlewares.AuthUserID(c)
+
+	user, err := passkeyUser(userID)
+	if err != nil || user == nil {
+		utils.HandleError(c, utils.ErrFindUser, http.StatusInternalServerError)
+		return nil
+	}
+
+	creation, session, err := passkeys.BeginRegistration(passkeys.Default, user)
+	if err != nil {
+		utils.HandleError(c, utils.ErrPasskey, http.StatusInternalServerError)
+		return nil
+	}
+
+	if err := utils.SaveWebAuthnCeremony(userID, *session); err != nil {
+		utils.HandleError(c, utils.ErrPasskey, http.StatusInternalServerError)
+		return nil
+	}
+
+	return c.Status(http.StatusOK).JSON(creation)
+}
+
+// FinishPasskeyRegistrationHandler adds the passkey the authenticator created
+// with the options of BeginPasskeyRegistrationHandler.
+func FinishPasskeyRegistrationHandler(c *fiber.Ctx) error {
+	userID := middlewares.AuthUserID(c)
+
+	var requestBody schemas.PasskeyRegistrationRequest
+	if err := json.Unmarshal(c.Body(), &requestBody); err != nil {
+		utils.HandleError(c, utils.ErrDecodeRequest, http.StatusBadRequest)
+		return nil
+	}
+
+	response, err := protocol.ParseCredentialCreationResponseBody(bytes.NewReader(requestBody.Credential))
+	if err != nil {
+		utils.HandleError(c, utils.ErrInvalidPasskey, http.StatusBadRequest)
+		return nil
+	}
+
+	session, err := utils.TakeWebAuthnCeremony(response.Response.CollectedClientData.Challenge, userID)
+	if err != nil {
+		utils.HandleError(c, utils.ErrPasskey, http.StatusInternalServerError)
+		return nil
+	}
+	if session == nil {
+		utils.HandleError(c, utils.ErrInvalidPasskey, http.StatusBadRequest)
+		return nil
+	}
+
+	user, err := passkeyUser(userID)
+	if err != nil || user == nil {
+		utils.HandleError(c, utils.ErrFindUser, http.StatusInternalServerError)
+		return nil
+	}
+
+	credential, err := passkeys.FinishRegistration(passkeys.Default, user, *session, response)
+	if err != nil {
+		utils.HandleError(c, utils.ErrInvalidPasskey, http.StatusBadRequest)
+		return nil
+	}
+
+	name := strings.TrimSpace(requestBody.Name)
+	if name == "" {
+		name = "Passkey"
+	}
+
+	passkey, err := utils.SavePasskey(userID, name, *credential)
+	if err != nil {
+		if errors.Is(err, utils.ErrPasskeyExists) {
+			utils.HandleError(c, err, http.StatusConflict)
+			return nil
+		}
+		utils.HandleError(c, utils.ErrPasskey, http.StatusInternalServerError)
+		return nil
+	}
+
+	return c.Status(http.StatusCreated).JSON(fiber.Map{
+		"message": "Passkey added.",
+		"passkey": passkey,
+	})
+}
+
+// GetPasskeysHandler lists the passkeys of the user.
+func GetPasskeysHandler(c *fiber.Ctx) error {
+	userPasskeys, err := utils.GetUserPasskeys(middlewares.AuthUserID(c))
+	if err != nil {
+		utils.HandleError(c, utils.ErrPasskey, http.StatusInternalServerError)
+		return nil
+	}
+
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"passkeys": userPasskeys,
+	})
+}
+
+// DeletePasskeyHandler removes one of the passkeys of the user. It stops
+// logging in right away.
+func DeletePasskeyHandler(c *fiber.Ctx) error {
+	credentialID, err := base64.RawURLEncoding.DecodeString(c.Params("id"))
+	if err != nil {
+		utils.HandleError(c, utils.ErrPasskeyNotFound, http.StatusNotFound)
+		return nil
+	}
+
+	if err := utils.DeletePasskey(middlewares.AuthUserID(c), credentialID); err != nil {
+		if errors.Is(err, utils.ErrPasskeyNotFound) {
+			utils.HandleError(c, err, http.StatusNotFound)
+			return nil
+		}
+		utils.HandleError(c, utils.ErrPasskey, http.StatusInternalServerError)
+		return nil
+	}
+
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"message": "Passkey removed.",
+	})
+}
+
+// BeginPasskeyLoginHandler returns the options to pass to
+// navigator.credentials.get to log in with a passkey.
+func BeginPasskeyLoginHandler(c *fiber.Ctx) error {
+	assertion, session, err := passkeys.BeginLogin(passkeys.Default)
+	if err != nil {
+		utils.HandleError(c, utils.ErrPasskey, http.StatusInternalServerError)
+		return nil
+	}
+
+	if err := utils.SaveWebAuthnCeremony("", *session); err != nil {
+		utils.HandleError(c, utils.ErrPasskey, http.StatusInternalServerError)
+		return nil
+	}
+
+	return c.Status(http.StatusOK).JSON(assertion)
+}
+
+// FinishPasskeyLoginHandler logs in the user whose passkey signed the
+// options of BeginPasskeyLoginHandler. The passkey verified the user itself,
+// so two-factor authentication is not asked for.
+func FinishPasskeyLoginHandler(c *fiber.Ctx) error {
+	var requestBody schemas.PasskeyLoginRequest
+	if err := json.Unmarshal(c.Body(), &requestBody); err != nil {
+		utils.HandleError(c, utils.ErrDecodeRequest, http.StatusBadRequest)
+		return nil
+	}
+
+	response, err := protocol.ParseCredentialRequestResponseBody(bytes.NewReader(requestBody.Credential))
+	if err != nil {
+		utils.HandleError(c, utils.ErrInvalidPasskey, http.StatusUnauthorized)
+		return nil
+	}
+
+	session, err := utils.TakeWebAuthnCeremony(response.Response.CollectedClientData.Challenge, "")
+	if err != nil {
+		utils.HandleError(c, utils.ErrPasskey, http.StatusInternalServerError)
+		return nil
+	}
+	if session == nil {
+		utils.HandleError(c, utils.ErrInvalidPasskey, http.StatusUnauthorized)
+		return nil
+	}
+
+	lookup := func(credentialID, userHandle []byte) (*passkeys.User, error) {
+		if _, err := uuid.Parse(string(userHandle)); err != nil {
+			return nil, nil
+		}
+		return passkeyUser(string(userHandle))
+	}
+
+	passkeyOwner, credential, err := passkeys.FinishLogin(passkeys.Default, *session, response, lookup)
+	if err != nil {
+		utils.HandleError(c, utils.ErrInvalidPasskey, http.StatusUnauthorized)
+		return nil
+	}
+
+	if err := utils.UsePasskey(*credential); err != nil {
+		utils.HandleError(c, utils.ErrPasskey, http.StatusInternalServerError)
+		return nil
+	}
+
+	user, err := utils.FindUserById(passkeyOwner.ID)
+	if err != nil {
+		utils.HandleError(c, utils.ErrFindUser, http.StatusInternalServerError)
+		return nil
+	}
+
+	return completeLogin(c, *user, passkeyOwner.ID)
+}
+
+// passkeyUser returns userID as authenticators see it, with their passkeys.
+// It returns nil without an error when the user does not exist.
+func passkeyUser(userID string) (*passkeys.User, error) {
+	user, err := utils.FindUserById(userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	userPasskeys, err := utils.GetUserPasskeys(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	credentials := make([]webauthn.Credential, len(userPasskeys))
+	for i, passkey := range userPasskeys {
+		credentials[i] = passkey.Credential
+	}
+
+	return &passkeys.User{
+		ID:          userID,
+		Name:        user.Username,
+		DisplayName: strings.TrimSpace(user.FirstName + " " + user.LastName),
+		Credentials: credentials,
+	}, nil
+}
@@ -0,0 +1,129 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"social_api/libs"
+	"social_api/mailer"
+	"social_api/passwords"
+	"social_api/schemas"
+	"social_api/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ForgotPasswordHandler emails a link to reset the password to the account
+// with the given email. The response is the same whether the account exists
+// or not, and the email is sent in the background so it takes as long too.
+func ForgotPasswordHandler(c *fiber.Ctx) error {
+	var requestBody schemas.ForgotPasswordRequest
+	if err := json.Unmarshal(c.Body(), &requestBody); err != nil {
+		utils.HandleError(c, utils.ErrDecodeRequest, http.StatusBadRequest)
+		return nil
+	}
+
+	go func() {
+		if err := sendPasswordResetEmail(requestBody.Email); err != nil {
+			fmt.Println("Error sending password reset email:", err)
+		}
+	}()
+
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"message": "If an account uses that email, a link to reset the password was sent to it.",
+	})
+}
+
+// ResetPasswordHandler sets a new password with the token of a reset link.
+// Every session of the user is logged out.
+func ResetPasswordHandler(c *fiber.Ctx) error {
+	var requestBody schemas.ResetPasswordRequest
+	if err := json.Unmarshal(c.Body(), &requestBody); err != nil {
+		utils.HandleError(c, utils.ErrDecodeRequest, http.StatusBadRequest)
+		return nil
+	}
+
+	tokenHash := libs.HashSecretToken(requestBody.Token)
+
+	userID, err := utils.FindPasswordResetUser(tokenHash)
+	if err != nil {
+		if errors.Is(err, utils.ErrInvalidResetToken) {
+			utils.HandleError(c, err, http.StatusBadRequest)
+			return nil
+		}
+		utils.HandleError(c, utils.ErrResetPassword, http.StatusInternalServerError)
+		return nil
+	}
+
+	reused, err := utils.IsPasswordReused(userID, requestBody.Password, passwords.Default.HistorySize)
+	if err != nil {
+		utils.HandleError(c, utils.ErrResetPassword, http.StatusInternalServerError)
+		return nil
+	}
+	if reused {
+		utils.HandleError(c, utils.ErrPasswordReused, http.StatusBadRequest)
+		return nil
+	}
+
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(requestBody.Password), bcrypt.DefaultCost)
+	if err != nil {
+		utils.HandleError(c, utils.ErrPasswordHash, http.StatusInternalServerError)
+		return nil
+	}
+
+	if _, err := utils.ResetPassword(tokenHash, string(passwordHash)); err != nil {
+		if errors.Is(err, utils.ErrInvalidResetToken) {
+			utils.HandleError(c, err, http.StatusBadRequest)
+			return nil
+		}
+		utils.HandleError(c, utils.ErrResetPassword, http.StatusInternalServerError)
+		return nil
+	}
+
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"message": "Password reset successfully. Please log in again.",
+	})
+}
+
+func sendPasswordResetEmail(email string) error {
+	user, err := utils.FindUserByEmail(email)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return nil
+	}
+
+	token, tokenHash, err := libs.GenerateSecretToken()
+	if err != nil {
+		return err
+	}
+
+	if err := utils.CreatePasswordReset(user.ID.String, tokenHash, time.Now().UTC().Add(libs.PasswordResetLifetime)); err != nil {
+		return err
+	}
+
+	link := passwordResetURL() + "?token=" + url.QueryEscape(token)
+
+	return mailer.Default.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: "Open this link to choose a new password:\n\n" + link + "\n\n" +
+			"It works once and for 1 hour. If you did not ask to reset your password, you can ignore this email.",
+	})
+}
+
+// passwordResetURL is the page of the client app that asks for the new
+// password and sends it to /api/password/reset along with the token.
+func passwordResetURL() string {
+	if resetURL := os.Getenv("PASSWORD_RESET_URL"); resetURL != "" {
+		return resetURL
+	}
+	return appBaseURL() + "/reset-password"
+}
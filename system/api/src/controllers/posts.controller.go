@@ -0,0 +1,188 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"social_api/feed"
+	"social_api/middlewares"
+	"social_api/models"
+	"social_api/schemas"
+	"social_api/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+func CreatePostHandler(c *fiber.Ctx) error {
+	authorID := middlewares.AuthUserID(c)
+
+	var requestBody schemas.CreatePostRequest
+	if err := json.Unmarshal(c.Body(), &requestBody); err != nil {
+		utils.HandleError(c, utils.ErrDecodeRequest, http.StatusBadRequest)
+		return nil
+	}
+
+	post, err := utils.SavePost(models.Post{
+		AuthorID:    authorID,
+		Title:       requestBody.Title,
+		Description: requestBody.Description,
+		Images:      requestBody.Images,
+		Videos:      requestBody.Videos,
+	})
+	if err != nil {
+		utils.HandleError(c, utils.ErrSavePost, http.StatusInternalServerError)
+		return nil
+	}
+
+	notifyFeed("post creation", func() error { return feed.Default.PostCreated(*post) })
+
+	return c.Status(http.StatusCreated).JSON(fiber.Map{
+		"message": "Post created successfully.",
+		"post":    post,
+	})
+}
+
+func GetPostsHandler(c *fiber.Ctx) error {
+	authorID := c.Query("author")
+	if authorID != "" {
+		if _, err := uuid.Parse(authorID); err != nil {
+			utils.HandleError(c, utils.ErrInvalidID, http.StatusBadRequest)
+			return nil
+		}
+	}
+
+	page, err := utils.ParsePage(c)
+	if err != nil {
+		utils.HandleError(c, err, http.StatusBadRequest)
+		return nil
+	}
+
+	posts, nextCursor, err := utils.GetPosts(authorID, page)
+	if err != nil {
+		utils.HandleError(c, utils.ErrFindPost, http.StatusInternalServerError)
+		return nil
+	}
+
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"posts":       posts,
+		"next_cursor": nextCursor,
+	})
+}
+
+func GetPostHandler(c *fiber.Ctx) error {
+	post, ok := findPostFromParams(c)
+	if !ok {
+		return nil
+	}
+
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"post": post,
+	})
+}
+
+func UpdatePostHandler(c *fiber.Ctx) error {
+	post, ok := findOwnPostFromParams(c)
+	if !ok {
+		return nil
+	}
+
+	var requestBody schemas.UpdatePostRequest
+	if err := json.Unmarshal(c.Body(), &requestBody); err != nil {
+		utils.HandleError(c, utils.ErrDecodeRequest, http.StatusBadRequest)
+		return nil
+	}
+
+	if requestBody.Title != nil {
+		post.Title = *requestBody.Title
+	}
+	if requestBody.Description != nil {
+		post.Description = *requestBody.Description
+	}
+	if requestBody.Images != nil {
+		post.Images = *requestBody.Images
+	}
+	if requestBody.Videos != nil {
+		post.Videos = *requestBody.Videos
+	}
+
+	updatedPost, err := utils.UpdatePost(*post)
+	if err != nil {
+		utils.HandleError(c, utils.ErrUpdatePost, http.StatusInternalServerError)
+		return nil
+	}
+
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"message": "Post updated successfully.",
+		"post":    updatedPost,
+	})
+}
+
+func DeletePostHandler(c *fiber.Ctx) error {
+	post, ok := findOwnPostFromParams(c)
+	if !ok {
+		return nil
+	}
+
+	if err := utils.DeletePost(post.ID); err != nil {
+		utils.HandleError(c, utils.ErrDeletePost, http.StatusInternalServerError)
+		return nil
+	}
+
+	notifyFeed("post deletion", func() error { return feed.Default.PostDeleted(*post) })
+
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"message": "Post deleted successfully.",
+	})
+}
+
+// notifyFeed runs a feed notification, which may have to fan out to many
+// followers, and logs its error since the request has already succeeded. It
+// runs before the response, so that the notifications of a post reach the
+// feed in the order its author made the changes.
+func notifyFeed(event string, notify func() error) {
+	if err := notify(); err != nil {
+		fmt.Println("Error updating feeds after "+event+":", err)
+	}
+}
+
+// findPostFromParams loads the post named by the :id param. When it returns
+// false the error response has already been written.
+func findPostFromParams(c *fiber.Ctx) (*models.Post, bool) {
+	postID := c.Params("id")
+	if _, err := uuid.Parse(postID); err != nil {
+		utils.HandleError(c, utils.ErrInvalidID, http.StatusBadRequest)
+		return nil, false
+	}
+
+	post, err := utils.FindPostById(postID)
+	if err != nil {
+		utils.HandleError(c, utils.ErrFindPost, http.StatusInternalServerError)
+		return nil, false
+	}
+
+	if post == nil {
+		utils.HandleError(c, utils.ErrPostNotFound, http.StatusNotFound)
+		return nil, false
+	}
+
+	return post, true
+}
+
+// findOwnPostFromParams works like findPostFromParams but also requires the
+// session user to be the author of the post.
+func findOwnPostFromParams(c *fiber.Ctx) (*models.Post, bool) {
+	userID := middlewares.AuthUserID(c)
+
+	post, ok := findPostFromParams(c)
+	if !ok {
+		return nil, false
+	}
+
+	if post.AuthorID != userID {
+		utils.HandleError(c, utils.ErrUnauthorized, http.StatusForbidden)
+		return nil, false
+	}
+
+	return post, true
+}
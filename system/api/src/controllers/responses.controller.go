@@ -0,0 +1,193 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"social_api/middlewares"
+	"social_api/models"
+	"social_api/schemas"
+	"social_api/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+func CreateResponseHandler(c *fiber.Ctx) error {
+	authorID := middlewares.AuthUserID(c)
+
+	post, ok := findPostFromParams(c)
+	if !ok {
+		return nil
+	}
+
+	var requestBody schemas.CreateResponseRequest
+	if err := json.Unmarshal(c.Body(), &requestBody); err != nil {
+		utils.HandleError(c, utils.ErrDecodeRequest, http.StatusBadRequest)
+		return nil
+	}
+
+	var parentID *string
+	if requestBody.ParentID != "" {
+		parent, err := utils.FindResponseById(requestBody.ParentID)
+		if err != nil {
+			utils.HandleError(c, utils.ErrFindResponse, http.StatusInternalServerError)
+			return nil
+		}
+
+		if parent == nil || parent.PostID != post.ID {
+			utils.HandleError(c, utils.ErrInvalidParentResponse, http.StatusBadRequest)
+			return nil
+		}
+
+		parentID = &parent.ID
+	}
+
+	response, err := utils.SaveResponse(models.Response{
+		PostID:   post.ID,
+		ParentID: parentID,
+		AuthorID: authorID,
+		Content:  requestBody.Content,
+		Images:   requestBody.Images,
+		Videos:   requestBody.Videos,
+	})
+	if err != nil {
+		utils.HandleError(c, utils.ErrSaveResponse, http.StatusInternalServerError)
+		return nil
+	}
+
+	return c.Status(http.StatusCreated).JSON(fiber.Map{
+		"message":  "Response created successfully.",
+		"response": response,
+	})
+}
+
+// GetResponsesHandler lists the responses of a post. With ?format=flat it
+// returns a page of responses oldest first with their parentId, otherwise a
+// page of top level responses with their replies nested.
+func GetResponsesHandler(c *fiber.Ctx) error {
+	format := c.Query("format", "tree")
+	if format != "tree" && format != "flat" {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "The format must be either 'tree' or 'flat'.",
+		})
+	}
+
+	post, ok := findPostFromParams(c)
+	if !ok {
+		return nil
+	}
+
+	page, err := utils.ParsePage(c)
+	if err != nil {
+		utils.HandleError(c, err, http.StatusBadRequest)
+		return nil
+	}
+
+	var responses []*models.Response
+	var nextCursor *string
+	if format == "tree" {
+		responses, nextCursor, err = utils.GetPostResponseThreads(post.ID, page)
+	} else {
+		responses, nextCursor, err = utils.GetPostResponses(post.ID, page)
+	}
+	if err != nil {
+		utils.HandleError(c, utils.ErrFindResponse, http.StatusInternalServerError)
+		return nil
+	}
+
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"responses":   responses,
+		"next_cursor": nextCursor,
+	})
+}
+
+func UpdateResponseHandler(c *fiber.Ctx) error {
+	response, ok := findOwnResponseFromParams(c)
+	if !ok {
+		return nil
+	}
+
+	var requestBody schemas.UpdateResponseRequest
+	if err := json.Unmarshal(c.Body(), &requestBody); err != nil {
+		utils.HandleError(c, utils.ErrDecodeRequest, http.StatusBadRequest)
+		return nil
+	}
+
+	if requestBody.Content != nil {
+		response.Content = *requestBody.Content
+	}
+	if requestBody.Images != nil {
+		response.Images = *requestBody.Images
+	}
+	if requestBody.Videos != nil {
+		response.Videos = *requestBody.Videos
+	}
+
+	updatedResponse, err := utils.UpdateResponse(*response)
+	if err != nil {
+		utils.HandleError(c, utils.ErrUpdateResponse, http.StatusInternalServerError)
+		return nil
+	}
+
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"message":  "Response updated successfully.",
+		"response": updatedResponse,
+	})
+}
+
+func DeleteResponseHandler(c *fiber.Ctx) error {
+	response, ok := findOwnResponseFromParams(c)
+	if !ok {
+		return nil
+	}
+
+	if err := utils.DeleteResponse(response.ID); err != nil {
+		utils.HandleError(c, utils.ErrDeleteResponse, http.StatusInternalServerError)
+		return nil
+	}
+
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"message": "Response deleted successfully.",
+	})
+}
+
+// findResponseFromParams loads the response named by the :id param. When it
+// returns false the error response has already been written.
+func findResponseFromParams(c *fiber.Ctx) (*models.Response, bool) {
+	responseID := c.Params("id")
+	if _, err := uuid.Parse(responseID); err != nil {
+		utils.HandleError(c, utils.ErrInvalidID, http.StatusBadRequest)
+		return nil, false
+	}
+
+	response, err := utils.FindResponseById(responseID)
+	if err != nil {
+		utils.HandleError(c, utils.ErrFindResponse, http.StatusInternalServerError)
+		return nil, false
+	}
+
+	if response == nil {
+		utils.HandleError(c, utils.ErrResponseNotFound, http.StatusNotFound)
+		return nil, false
+	}
+
+	return response, true
+}
+
+// findOwnResponseFromParams works like findResponseFromParams but also
+// requires the session user to be the author of the response.
+func findOwnResponseFromParams(c *fiber.Ctx) (*models.Response, bool) {
+	userID := middlewares.AuthUserID(c)
+
+	response, ok := findResponseFromParams(c)
+	if !ok {
+		return nil, false
+	}
+
+	if response.AuthorID != userID {
+		utils.HandleError(c, utils.ErrUnauthorized, http.StatusForbidden)
+		return nil, false
+	}
+
+	return response, true
+}
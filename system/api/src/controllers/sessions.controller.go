@@ -0,0 +1,63 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"social_api/middlewares"
+	"social_api/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// GetSessionsHandler lists where the user is logged in. The session the
+// request was made with is marked as current.
+func GetSessionsHandler(c *fiber.Ctx) error {
+	claims := middlewares.AuthClaims(c)
+
+	page, err := utils.ParsePage(c)
+	if err != nil {
+		utils.HandleError(c, err, http.StatusBadRequest)
+		return nil
+	}
+
+	sessions, nextCursor, err := utils.GetUserSessions(claims.UserID, page)
+	if err != nil {
+		utils.HandleError(c, utils.ErrFindSessions, http.StatusInternalServerError)
+		return nil
+	}
+
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == claims.SessionID
+	}
+
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"sessions":    sessions,
+		"next_cursor": nextCursor,
+	})
+}
+
+// RevokeSessionHandler logs the user out of one of their sessions.
+func RevokeSessionHandler(c *fiber.Ctx) error {
+	userID := middlewares.AuthUserID(c)
+
+	sessionID := c.Params("id")
+	if _, err := uuid.Parse(sessionID); err != nil {
+		utils.HandleError(c, utils.ErrInvalidID, http.StatusBadRequest)
+		return nil
+	}
+
+	if err := utils.RevokeSession(userID, sessionID); err != nil {
+		if errors.Is(err, utils.ErrSessionNotFound) {
+			utils.HandleError(c, err, http.StatusNotFound)
+			return nil
+		}
+		utils.HandleError(c, utils.ErrRevokeSession, http.StatusInternalServerError)
+		return nil
+	}
+
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"message": "Session revoked successfully.",
+	})
+}
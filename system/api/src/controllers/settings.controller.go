@@ -1,30 +1,26 @@
 package controllers
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
+	"social_api/imaging"
+	"social_api/middlewares"
+	"social_api/passwords"
 	"social_api/schemas"
+	"social_api/storage"
 	"social_api/utils"
+	"strconv"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 )
 
 func PrivateProfileHandler(c *fiber.Ctx) error {
-	token := c.Get("session")
-	if token == "" {
-		utils.HandleError(c, utils.ErrUnauthorized, http.StatusUnauthorized)
-		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
-			"err": "Missing authorization header.",
-		})
-	}
-
-	id, err := utils.ExtractUserIDFromToken(token)
-	if err != nil {
-		utils.HandleError(c, utils.ErrUnauthorized, http.StatusUnauthorized)
-		return c.Status(401).JSON(fiber.Map{"error": err.Error()})
-	}
+	id := middlewares.AuthUserID(c)
 
 	user, err := utils.FindUserById(id)
 	if err != nil {
@@ -32,24 +28,27 @@ func PrivateProfileHandler(c *fiber.Ctx) error {
 		return errors.New("Error finding the user")
 	}
 
+	followersCount, followingCount, err := utils.CountFollows(user.ID.String)
+	if err != nil {
+		utils.HandleError(c, utils.ErrFindFollows, http.StatusInternalServerError)
+		return nil
+	}
+
+	userInfo := utils.UserWithoutPassword(*user, user.ID.String)
+	userInfo["followersCount"] = followersCount
+	userInfo["followingCount"] = followingCount
+
 	response := map[string]interface{}{
 		"message": user.Username + " Profile",
-		"user":    utils.UserWithoutPassword(*user, user.ID.String),
+		"user":    userInfo,
 	}
 
 	return c.Status(200).JSON(response)
 }
 
 func UpdateUsernameHandler(c *fiber.Ctx) error {
-	tokenString := c.Get("session")
-	if tokenString == "" {
-		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "No token provided"})
-	}
+	id := middlewares.AuthUserID(c)
 
-	id, err := utils.ParseToken(tokenString)
-	if err != nil {
-		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token"})
-	}
 	var requestBody schemas.UpdateUsernameRequest
 	if err := json.Unmarshal([]byte(c.Body()), &requestBody); err != nil {
 		utils.HandleError(c, utils.ErrDecodeRequest, http.StatusBadRequest)
@@ -75,16 +74,10 @@ func UpdateUsernameHandler(c *fiber.Ctx) error {
 	return c.Status(http.StatusOK).JSON(response)
 }
 
+// UpdatePasswordHandler changes the password of the session user, who has to
+// send their current password too.
 func UpdatePasswordHandler(c *fiber.Ctx) error {
-	tokenString := c.Get("session")
-	if tokenString == "" {
-		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "No token provided"})
-	}
-
-	id, err := utils.ParseToken(tokenString)
-	if err != nil {
-		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token"})
-	}
+	id := middlewares.AuthUserID(c)
 
 	var requestBody schemas.UpdatePasswordRequest
 	if err := json.Unmarshal([]byte(c.Body()), &requestBody); err != nil {
@@ -92,12 +85,28 @@ func UpdatePasswordHandler(c *fiber.Ctx) error {
 		return errors.New("Error decoding request body.")
 	}
 
-	password := requestBody.Password
-	if password == "" {
-		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "New password cannot be empty"})
+	user, err := utils.FindUserById(id)
+	if err != nil {
+		utils.HandleError(c, utils.ErrFindUser, http.StatusInternalServerError)
+		return nil
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(requestBody.CurrentPassword)); err != nil {
+		utils.HandleError(c, utils.ErrWrongPassword, http.StatusForbidden)
+		return nil
+	}
+
+	reused, err := utils.IsPasswordReused(id, requestBody.Password, passwords.Default.HistorySize)
+	if err != nil {
+		utils.HandleError(c, utils.ErrUpdatePassword, http.StatusInternalServerError)
+		return nil
+	}
+	if reused {
+		utils.HandleError(c, utils.ErrPasswordReused, http.StatusBadRequest)
+		return nil
 	}
 
-	passwordHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(requestBody.Password), bcrypt.DefaultCost)
 	if err != nil {
 		utils.HandleError(c, utils.ErrPasswordHash, http.StatusInternalServerError)
 		return errors.New("Error generating password hash.")
@@ -105,8 +114,8 @@ func UpdatePasswordHandler(c *fiber.Ctx) error {
 
 	// Actualizar la password del usuario en la base de datos
 	if err := utils.UpdatePassword(id, string(passwordHash)); err != nil {
-		utils.HandleError(c, utils.ErrUpdateUsername, http.StatusInternalServerError)
-		return errors.New("Error updating username")
+		utils.HandleError(c, utils.ErrUpdatePassword, http.StatusInternalServerError)
+		return nil
 	}
 
 	// Respuesta exitosa
@@ -116,3 +125,93 @@ func UpdatePasswordHandler(c *fiber.Ctx) error {
 
 	return c.Status(http.StatusOK).JSON(response)
 }
+
+// pictureSizes are the sides, in pixels, of the square thumbnails generated
+// for profile pictures. The biggest one is stored as the user picture.
+var pictureSizes = []int{64, 256}
+
+// UpdatePictureHandler takes an image from the "picture" field of a multipart
+// form, crops it to a centered square and stores it in every size of
+// pictureSizes.
+func UpdatePictureHandler(c *fiber.Ctx) error {
+	id := middlewares.AuthUserID(c)
+
+	fileHeader, err := c.FormFile("picture")
+	if err != nil {
+		utils.HandleError(c, utils.ErrMissingFile, http.StatusBadRequest)
+		return nil
+	}
+
+	if fileHeader.Size > MaxImageSize {
+		utils.HandleError(c, utils.ErrFileTooLarge, http.StatusRequestEntityTooLarge)
+		return nil
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		utils.HandleError(c, utils.ErrUpdatePicture, http.StatusInternalServerError)
+		return nil
+	}
+	defer file.Close()
+
+	img, err := imaging.Decode(file)
+	if err != nil {
+		utils.HandleError(c, utils.ErrInvalidImage, http.StatusBadRequest)
+		return nil
+	}
+
+	// A new key for every upload keeps cached copies of the previous picture
+	// from being served.
+	version := uuid.NewString()
+	thumbnails := make(map[string]string, len(pictureSizes))
+	var pictureURL string
+
+	for _, size := range pictureSizes {
+		var buf bytes.Buffer
+		if err := imaging.EncodeJPEG(&buf, imaging.SquareThumbnail(img, size)); err != nil {
+			utils.HandleError(c, utils.ErrUpdatePicture, http.StatusInternalServerError)
+			return nil
+		}
+
+		key := fmt.Sprintf("avatars/%s/%s-%d.jpg", id, version, size)
+		url, err := storage.Backend.Save(key, &buf, "image/jpeg")
+		if err != nil {
+			utils.HandleError(c, utils.ErrUpdatePicture, http.StatusInternalServerError)
+			return nil
+		}
+
+		thumbnails[strconv.Itoa(size)] = url
+		pictureURL = url
+	}
+
+	if err := utils.UpdatePicture(id, pictureURL); err != nil {
+		utils.HandleError(c, utils.ErrUpdatePicture, http.StatusInternalServerError)
+		return nil
+	}
+
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"message":    "Picture updated successfully.",
+		"picture":    pictureURL,
+		"thumbnails": thumbnails,
+	})
+}
+
+func UpdateDescriptionHandler(c *fiber.Ctx) error {
+	id := middlewares.AuthUserID(c)
+
+	var requestBody schemas.UpdateDescriptionRequest
+	if err := json.Unmarshal(c.Body(), &requestBody); err != nil {
+		utils.HandleError(c, utils.ErrDecodeRequest, http.StatusBadRequest)
+		return nil
+	}
+
+	if err := utils.UpdateDescription(id, requestBody.Description); err != nil {
+		utils.HandleError(c, utils.ErrUpdateDescription, http.StatusInternalServerError)
+		return nil
+	}
+
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"message":     "Description updated successfully.",
+		"description": requestBody.Description,
+	})
+}
@@ -4,9 +4,12 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"social_api/feed"
+	"social_api/middlewares"
 	"social_api/utils"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
 )
 
 func ProfileHandler(c *fiber.Ctx) error {
@@ -23,9 +26,40 @@ func ProfileHandler(c *fiber.Ctx) error {
 		return errors.New("Error finding the user")
 	}
 
+	followersCount, followingCount, err := utils.CountFollows(user.ID.String)
+	if err != nil {
+		utils.HandleError(c, utils.ErrFindFollows, http.StatusInternalServerError)
+		return nil
+	}
+
+	userInfo := utils.UserWithoutPasswordAndEmail(*user, user.ID.String)
+	userInfo["followersCount"] = followersCount
+	userInfo["followingCount"] = followingCount
+
 	response := map[string]interface{}{
 		"message": user.Username + " Profile",
-		"user":    utils.UserWithoutPasswordAndEmail(*user, user.ID.String),
+		"user":    userInfo,
+	}
+
+	// The relationship is only known when a valid session is sent, and the
+	// profile stays public otherwise.
+	if viewerID := middlewares.AuthUserID(c); viewerID != "" && viewerID != user.ID.String {
+		youFollow, err := utils.IsFollowing(viewerID, user.ID.String)
+		if err != nil {
+			utils.HandleError(c, utils.ErrFindFollows, http.StatusInternalServerError)
+			return nil
+		}
+
+		followsYou, err := utils.IsFollowing(user.ID.String, viewerID)
+		if err != nil {
+			utils.HandleError(c, utils.ErrFindFollows, http.StatusInternalServerError)
+			return nil
+		}
+
+		response["relationship"] = fiber.Map{
+			"youFollow":  youFollow,
+			"followsYou": followsYou,
+		}
 	}
 
 	return c.Status(200).JSON(response)
@@ -40,19 +74,7 @@ func FollowUserHandler(c *fiber.Ctx) error {
 		})
 	}
 
-	token := c.Get("session")
-	if token == "" {
-		utils.HandleError(c, utils.ErrUnauthorized, http.StatusUnauthorized)
-		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
-			"err": "Missing authorization header.",
-		})
-	}
-
-	followerID, err := utils.ExtractUserIDFromToken(token)
-	if err != nil {
-		utils.HandleError(c, utils.ErrUnauthorized, http.StatusUnauthorized)
-		return c.Status(401).JSON(fiber.Map{"error": err.Error()})
-	}
+	followerID := middlewares.AuthUserID(c)
 
 	isAlreadyFollowing, err := utils.IsFollowing(followerID, followedID)
 	if err != nil {
@@ -74,6 +96,8 @@ func FollowUserHandler(c *fiber.Ctx) error {
 		})
 	}
 
+	notifyFeed("follow", func() error { return feed.Default.FollowChanged(followerID) })
+
 	return c.Status(200).JSON(fiber.Map{
 		"success": fmt.Sprintf("User %s followed successfully", followedID),
 	})
@@ -88,19 +112,7 @@ func UnFollowUserHandler(c *fiber.Ctx) error {
 		})
 	}
 
-	token := c.Get("session")
-	if token == "" {
-		utils.HandleError(c, utils.ErrUnauthorized, http.StatusUnauthorized)
-		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
-			"err": "Missing authorization header.",
-		})
-	}
-
-	followerID, err := utils.ExtractUserIDFromToken(token)
-	if err != nil {
-		utils.HandleError(c, utils.ErrUnauthorized, http.StatusUnauthorized)
-		return c.Status(401).JSON(fiber.Map{"error": err.Error()})
-	}
+	followerID := middlewares.AuthUserID(c)
 
 	isAlreadyNotFollowing, err := utils.IsNotFollowing(followerID, followedID)
 	if err != nil {
@@ -122,6 +134,8 @@ func UnFollowUserHandler(c *fiber.Ctx) error {
 		})
 	}
 
+	notifyFeed("unfollow", func() error { return feed.Default.FollowChanged(followerID) })
+
 	return c.Status(200).JSON(fiber.Map{
 		"success": fmt.Sprintf("User %s has successfully unfollowed", followedID),
 	})
@@ -129,8 +143,18 @@ func UnFollowUserHandler(c *fiber.Ctx) error {
 
 func GetFollowersHandler(c *fiber.Ctx) error {
 	id := c.Params("id")
+	if _, err := uuid.Parse(id); err != nil {
+		utils.HandleError(c, utils.ErrInvalidID, http.StatusBadRequest)
+		return nil
+	}
+
+	page, err := utils.ParsePage(c)
+	if err != nil {
+		utils.HandleError(c, err, http.StatusBadRequest)
+		return nil
+	}
 
-	followers, err := utils.GetFollowers(id)
+	followers, nextCursor, err := utils.GetFollowers(id, page)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error":   "Coudn't get followers",
@@ -139,6 +163,34 @@ func GetFollowersHandler(c *fiber.Ctx) error {
 	}
 
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"followers": followers,
+		"followers":   followers,
+		"next_cursor": nextCursor,
+	})
+}
+
+func GetFollowingHandler(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if _, err := uuid.Parse(id); err != nil {
+		utils.HandleError(c, utils.ErrInvalidID, http.StatusBadRequest)
+		return nil
+	}
+
+	page, err := utils.ParsePage(c)
+	if err != nil {
+		utils.HandleError(c, err, http.StatusBadRequest)
+		return nil
+	}
+
+	following, nextCursor, err := utils.GetFollowing(id, page)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Coudn't get following",
+			"message": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"following":   following,
+		"next_cursor": nextCursor,
 	})
 }
@@ -0,0 +1,245 @@
+package controllers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"time"
+
+	"social_api/libs"
+	"social_api/middlewares"
+	"social_api/models"
+	"social_api/schemas"
+	"social_api/tokens"
+	"social_api/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/skip2/go-qrcode"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// recoveryCodeCount is how many recovery codes users get when they turn
+// two-factor authentication on.
+const recoveryCodeCount = 10
+
+// SetupTwoFactorHandler starts turning two-factor authentication on with a
+// new secret, returned along with the otpauth URI and its QR code for
+// authenticator apps. It is only on once confirmed with a code.
+func SetupTwoFactorHandler(c *fiber.Ctx) error {
+	user, err := utils.FindUserById(middlewares.AuthUserID(c))
+	if err != nil {
+		utils.HandleError(c, utils.ErrFindUser, http.StatusInternalServerError)
+		return nil
+	}
+
+	secret, err := libs.GenerateTOTPSecret()
+	if err != nil {
+		utils.HandleError(c, utils.ErrTwoFactor, http.StatusInternalServerError)
+		return nil
+	}
+
+	if err := utils.SaveTOTPSecret(user.ID.String, secret); err != nil {
+		if errors.Is(err, utils.ErrTOTPAlreadyEnabled) {
+			utils.HandleError(c, err, http.StatusBadRequest)
+			return nil
+		}
+		utils.HandleError(c, utils.ErrTwoFactor, http.StatusInternalServerError)
+		return nil
+	}
+
+	uri := libs.TOTPURI(totpIssuer(), user.Username, secret)
+
+	png, err := qrcode.Encode(uri, qrcode.Medium, 256)
+	if err != nil {
+		utils.HandleError(c, utils.ErrTwoFactor, http.StatusInternalServerError)
+		return nil
+	}
+
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"secret":     secret,
+		"otpauthUri": uri,
+		"qrCode":     "data:image/png;base64," + base64.StdEncoding.EncodeToString(png),
+	})
+}
+
+// ConfirmTwoFactorHandler turns two-factor authentication on once the user
+// sends a code of their authenticator app, and returns their recovery codes.
+// They are only shown this once.
+func ConfirmTwoFactorHandler(c *fiber.Ctx) error {
+	userID := middlewares.AuthUserID(c)
+
+	var requestBody schemas.ConfirmTwoFactorRequest
+	if err := json.Unmarshal(c.Body(), &requestBody); err != nil {
+		utils.HandleError(c, utils.ErrDecodeRequest, http.StatusBadRequest)
+		return nil
+	}
+
+	totp, err := utils.FindTOTP(userID)
+	if err != nil {
+		utils.HandleError(c, utils.ErrTwoFactor, http.StatusInternalServerError)
+		return nil
+	}
+	if totp == nil || totp.Confirmed {
+		utils.HandleError(c, utils.ErrTOTPNotPending, http.StatusBadRequest)
+		return nil
+	}
+
+	step, ok := libs.ValidateTOTP(totp.Secret, requestBody.Code, time.Now())
+	if !ok {
+		utils.HandleError(c, utils.ErrInvalidTOTPCode, http.StatusBadRequest)
+		return nil
+	}
+
+	codes, err := libs.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		utils.HandleError(c, utils.ErrTwoFactor, http.StatusInternalServerError)
+		return nil
+	}
+
+	codeHashes := make([]string, len(codes))
+	for i, code := range codes {
+		codeHashes[i] = libs.HashSecretToken(code)
+	}
+
+	if err := utils.ConfirmTOTP(userID, step, codeHashes); err != nil {
+		if errors.Is(err, utils.ErrTOTPNotPending) {
+			utils.HandleError(c, err, http.StatusBadRequest)
+			return nil
+		}
+		utils.HandleError(c, utils.ErrTwoFactor, http.StatusInternalServerError)
+		return nil
+	}
+
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"message":        "Two-factor authentication enabled. Keep these recovery codes somewhere safe.",
+		"recovery_codes": codes,
+	})
+}
+
+// DisableTwoFactorHandler turns two-factor authentication off. It takes both
+// the password and a code, so a stolen session is not enough.
+func DisableTwoFactorHandler(c *fiber.Ctx) error {
+	userID := middlewares.AuthUserID(c)
+
+	var requestBody schemas.DisableTwoFactorRequest
+	if err := json.Unmarshal(c.Body(), &requestBody); err != nil {
+		utils.HandleError(c, utils.ErrDecodeRequest, http.StatusBadRequest)
+		return nil
+	}
+
+	user, err := utils.FindUserById(userID)
+	if err != nil {
+		utils.HandleError(c, utils.ErrFindUser, http.StatusInternalServerError)
+		return nil
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(requestBody.Password)); err != nil {
+		utils.HandleError(c, utils.ErrWrongPassword, http.StatusForbidden)
+		return nil
+	}
+
+	totp, err := utils.FindTOTP(userID)
+	if err != nil {
+		utils.HandleError(c, utils.ErrTwoFactor, http.StatusInternalServerError)
+		return nil
+	}
+	if totp == nil || !totp.Confirmed {
+		utils.HandleError(c, utils.ErrTOTPNotEnabled, http.StatusBadRequest)
+		return nil
+	}
+
+	valid, err := verifySecondFactor(totp, requestBody.Code)
+	if err != nil {
+		utils.HandleError(c, utils.ErrTwoFactor, http.StatusInternalServerError)
+		return nil
+	}
+	if !valid {
+		utils.HandleError(c, utils.ErrInvalidTOTPCode, http.StatusForbidden)
+		return nil
+	}
+
+	if err := utils.DisableTOTP(userID); err != nil {
+		utils.HandleError(c, utils.ErrTwoFactor, http.StatusInternalServerError)
+		return nil
+	}
+
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"message": "Two-factor authentication disabled.",
+	})
+}
+
+// LoginTwoFactorHandler finishes a login that needs a second factor. It takes
+// the token LoginHandler returned after checking the password, and a code of
+// the authenticator app or one of the recovery codes.
+func LoginTwoFactorHandler(c *fiber.Ctx) error {
+	var requestBody schemas.LoginTwoFactorRequest
+	if err := json.Unmarshal(c.Body(), &requestBody); err != nil {
+		utils.HandleError(c, utils.ErrDecodeRequest, http.StatusBadRequest)
+		return nil
+	}
+
+	claims, err := tokens.ParsePurposeToken(requestBody.MFAToken, tokens.PurposeMFA)
+	if err != nil {
+		utils.HandleError(c, utils.ErrInvalidMFAToken, http.StatusUnauthorized)
+		return nil
+	}
+
+	// Codes are guessed more easily than passwords, so their failures count
+	// like failed logins.
+	accountKey := "user:" + claims.Subject
+	if wait := tryLogin(c, accountKey); wait > 0 {
+		return tooManyLogins(c, wait)
+	}
+
+	totp, err := utils.FindTOTP(claims.Subject)
+	if err != nil {
+		abortLogin(c, accountKey)
+		utils.HandleError(c, utils.ErrTwoFactor, http.StatusInternalServerError)
+		return nil
+	}
+	if totp == nil || !totp.Confirmed {
+		utils.HandleError(c, utils.ErrInvalidMFAToken, http.StatusUnauthorized)
+		return nil
+	}
+
+	valid, err := verifySecondFactor(totp, requestBody.Code)
+	if err != nil {
+		abortLogin(c, accountKey)
+		utils.HandleError(c, utils.ErrTwoFactor, http.StatusInternalServerError)
+		return nil
+	}
+	if !valid {
+		utils.HandleError(c, utils.ErrInvalidTOTPCode, http.StatusUnauthorized)
+		return nil
+	}
+
+	succeedLogin(c, accountKey)
+
+	user, err := utils.FindUserById(claims.Subject)
+	if err != nil {
+		utils.HandleError(c, utils.ErrFindUser, http.StatusInternalServerError)
+		return nil
+	}
+
+	return completeLogin(c, *user, user.ID.String)
+}
+
+// verifySecondFactor checks code as a TOTP code of totp, or else as one of
+// the recovery codes of its user, and spends it.
+func verifySecondFactor(totp *models.TOTP, code string) (bool, error) {
+	if step, ok := libs.ValidateTOTP(totp.Secret, code, time.Now()); ok {
+		return utils.UseTOTPStep(totp.UserID, step)
+	}
+
+	return utils.UseRecoveryCode(totp.UserID, libs.HashSecretToken(libs.NormalizeRecoveryCode(code)))
+}
+
+// totpIssuer is the name authenticator apps show next to the codes.
+func totpIssuer() string {
+	if issuer := os.Getenv("TOTP_ISSUER"); issuer != "" {
+		return issuer
+	}
+	return "Social API"
+}
@@ -0,0 +1,93 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+
+	"social_api/mailer"
+	"social_api/middlewares"
+	"social_api/tokens"
+	"social_api/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// VerifyEmailHandler marks the email of the user as verified with the token
+// of the link sent to it.
+func VerifyEmailHandler(c *fiber.Ctx) error {
+	claims, err := tokens.ParsePurposeToken(c.Query("token"), tokens.PurposeVerifyEmail)
+	if err != nil {
+		utils.HandleError(c, utils.ErrInvalidEmailToken, http.StatusBadRequest)
+		return nil
+	}
+
+	verified, err := utils.VerifyEmail(claims.Subject, claims.Email)
+	if err != nil {
+		utils.HandleError(c, utils.ErrVerifyEmail, http.StatusInternalServerError)
+		return nil
+	}
+	if !verified {
+		utils.HandleError(c, utils.ErrInvalidEmailToken, http.StatusBadRequest)
+		return nil
+	}
+
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"message": "Email verified successfully.",
+	})
+}
+
+// ResendVerificationHandler sends the verification link to the session user
+// again.
+func ResendVerificationHandler(c *fiber.Ctx) error {
+	user, err := utils.FindUserById(middlewares.AuthUserID(c))
+	if err != nil {
+		utils.HandleError(c, utils.ErrFindUser, http.StatusInternalServerError)
+		return nil
+	}
+
+	if user.EmailVerified {
+		utils.HandleError(c, utils.ErrEmailAlreadyVerified, http.StatusBadRequest)
+		return nil
+	}
+
+	if err := sendVerificationEmail(user.ID.String, user.Email); err != nil {
+		fmt.Println("Error sending verification email:", err)
+		utils.HandleError(c, utils.ErrSendEmail, http.StatusInternalServerError)
+		return nil
+	}
+
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"message": "Verification email sent.",
+	})
+}
+
+func sendVerificationEmail(userID, email string) error {
+	token, err := tokens.GeneratePurposeToken(tokens.PurposeVerifyEmail, userID, email, tokens.VerificationTokenLifetime)
+	if err != nil {
+		return err
+	}
+
+	link := appBaseURL() + "/api/verify-email?token=" + url.QueryEscape(token)
+
+	return mailer.Default.Send(mailer.Message{
+		To:      email,
+		Subject: "Verify your email",
+		Body: "Open this link to verify your email:\n\n" + link + "\n\n" +
+			"It works for 24 hours. If you did not create an account, you can ignore this email.",
+	})
+}
+
+// appBaseURL is where the links sent by email point to.
+func appBaseURL() string {
+	if baseURL := os.Getenv("APP_BASE_URL"); baseURL != "" {
+		return baseURL
+	}
+
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "3000"
+	}
+	return "http://localhost:" + port
+}
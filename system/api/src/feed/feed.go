@@ -0,0 +1,67 @@
+package feed
+
+import (
+	"fmt"
+	"os"
+
+	"social_api/models"
+	"social_api/utils"
+)
+
+const (
+	// StrategyPull reads the timeline from the followers graph on every request.
+	StrategyPull = "pull"
+	// StrategyPush precomputes the timelines when posts are created.
+	StrategyPush = "push"
+
+	defaultTimelineLength = 800
+	// defaultMaxTimelines is how many timelines StrategyPush keeps in memory.
+	defaultMaxTimelines = 10000
+)
+
+// Builder builds the home timeline of a user. The controllers notify it of
+// every change that can alter a timeline, so strategies that keep state can
+// update it.
+type Builder interface {
+	PostCreated(post models.Post) error
+	PostDeleted(post models.Post) error
+	FollowChanged(followerID string) error
+	Timeline(userID string, cursor *utils.Cursor, limit int) ([]models.Post, error)
+}
+
+// Store is the data the builders read from.
+type Store interface {
+	// Followers returns the IDs of the users that follow authorID.
+	Followers(authorID string) ([]string, error)
+	// FollowingPosts returns the posts of the users followed by userID,
+	// newest first, starting after the cursor when there is one.
+	FollowingPosts(userID string, cursor *utils.Cursor, limit int) ([]models.Post, error)
+	// Posts returns the live posts among postIDs in the same order.
+	Posts(postIDs []string) ([]models.Post, error)
+}
+
+var Default Builder
+
+// Init sets Default to the strategy named by the FEED_STRATEGY environment
+// variable, reading from the database.
+func Init() error {
+	builder, err := New(os.Getenv("FEED_STRATEGY"), DBStore{})
+	if err != nil {
+		return err
+	}
+
+	Default = builder
+	return nil
+}
+
+// New returns the builder for strategy. An empty strategy means StrategyPull.
+func New(strategy string, store Store) (Builder, error) {
+	switch strategy {
+	case "", StrategyPull:
+		return NewPullBuilder(store), nil
+	case StrategyPush:
+		return NewPushBuilder(store, defaultTimelineLength, defaultMaxTimelines), nil
+	default:
+		return nil, fmt.Errorf("%w: %q", utils.ErrFeedStrategy, strategy)
+	}
+}
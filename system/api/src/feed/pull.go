@@ -0,0 +1,32 @@
+package feed
+
+import (
+	"social_api/models"
+	"social_api/utils"
+)
+
+// PullBuilder reads every timeline on demand from the store. It keeps no
+// state, so the notifications are no-ops.
+type PullBuilder struct {
+	store Store
+}
+
+func NewPullBuilder(store Store) *PullBuilder {
+	return &PullBuilder{store: store}
+}
+
+func (b *PullBuilder) PostCreated(post models.Post) error {
+	return nil
+}
+
+func (b *PullBuilder) PostDeleted(post models.Post) error {
+	return nil
+}
+
+func (b *PullBuilder) FollowChanged(followerID string) error {
+	return nil
+}
+
+func (b *PullBuilder) Timeline(userID string, cursor *utils.Cursor, limit int) ([]models.Post, error) {
+	return b.store.FollowingPosts(userID, cursor, limit)
+}
@@ -0,0 +1,292 @@
+package feed
+
+import (
+	"container/list"
+	"sort"
+	"sync"
+	"time"
+
+	"social_api/models"
+	"social_api/utils"
+)
+
+// PushBuilder keeps the newest posts of each timeline in memory and fans new
+// posts out to the timelines of the followers of their author when they are
+// created, so reading a timeline does not touch the followers graph.
+//
+// A timeline is only materialized the first time its owner reads it, from the
+// store, and it is dropped whenever its owner follows or unfollows someone.
+// Fan-out skips the followers without a materialized timeline, since they
+// will see the post once theirs is built. Only the maxTimelines timelines read
+// last are kept.
+type PushBuilder struct {
+	store        Store
+	maxLen       int
+	maxTimelines int
+
+	mu        sync.Mutex
+	timelines map[string]*list.Element
+	// recent holds the *timeline of every materialized timeline, the one read
+	// last first.
+	recent *list.List
+	// building holds the timelines being read from the store.
+	building map[string]*timelineBuild
+}
+
+type timelineEntry struct {
+	postID    string
+	createdAt time.Time
+}
+
+// before reports whether e comes before other in a timeline, which is ordered
+// like the database one: newest first, then by descending ID.
+func (e timelineEntry) before(other timelineEntry) bool {
+	if !e.createdAt.Equal(other.createdAt) {
+		return e.createdAt.After(other.createdAt)
+	}
+	return e.postID > other.postID
+}
+
+type timeline struct {
+	userID  string
+	entries []timelineEntry
+	// truncated is set when older posts exist but did not fit in entries.
+	truncated bool
+}
+
+// timelineBuild records what happened to a timeline while it was read from
+// the store, since the store may have been read before it happened.
+type timelineBuild struct {
+	done    chan struct{}
+	changes []timelineChange
+	// stale is set when the owner followed or unfollowed someone, which the
+	// store may not have shown yet.
+	stale bool
+}
+
+type timelineChange struct {
+	entry   timelineEntry
+	removed bool
+}
+
+func NewPushBuilder(store Store, maxLen, maxTimelines int) *PushBuilder {
+	return &PushBuilder{
+		store:        store,
+		maxLen:       maxLen,
+		maxTimelines: maxTimelines,
+		timelines:    make(map[string]*list.Element),
+		recent:       list.New(),
+		building:     make(map[string]*timelineBuild),
+	}
+}
+
+func (b *PushBuilder) PostCreated(post models.Post) error {
+	followers, err := b.store.Followers(post.AuthorID)
+	if err != nil {
+		return err
+	}
+
+	entry := timelineEntry{postID: post.ID, createdAt: post.CreatedAt}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, followerID := range followers {
+		if element, ok := b.timelines[followerID]; ok {
+			element.Value.(*timeline).insert(entry, b.maxLen)
+		} else if build, ok := b.building[followerID]; ok {
+			build.changes = append(build.changes, timelineChange{entry: entry})
+		}
+	}
+
+	return nil
+}
+
+func (b *PushBuilder) PostDeleted(post models.Post) error {
+	followers, err := b.store.Followers(post.AuthorID)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	entry := timelineEntry{postID: post.ID, createdAt: post.CreatedAt}
+
+	for _, followerID := range followers {
+		if element, ok := b.timelines[followerID]; ok {
+			element.Value.(*timeline).remove(post.ID)
+		} else if build, ok := b.building[followerID]; ok {
+			build.changes = append(build.changes, timelineChange{entry: entry, removed: true})
+		}
+	}
+
+	return nil
+}
+
+func (b *PushBuilder) FollowChanged(followerID string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if element, ok := b.timelines[followerID]; ok {
+		b.recent.Remove(element)
+		delete(b.timelines, followerID)
+	}
+	if build, ok := b.building[followerID]; ok {
+		build.stale = true
+	}
+	return nil
+}
+
+func (b *PushBuilder) Timeline(userID string, cursor *utils.Cursor, limit int) ([]models.Post, error) {
+	if err := b.materialize(userID); err != nil {
+		return nil, err
+	}
+
+	b.mu.Lock()
+	element, ok := b.timelines[userID]
+	var page []timelineEntry
+	exhausted := !ok
+	if ok {
+		t := element.Value.(*timeline)
+		page = t.after(cursor, limit)
+		exhausted = len(page) < limit && t.truncated
+	}
+	b.mu.Unlock()
+
+	// The page reaches past the oldest post kept in memory.
+	if exhausted {
+		return b.store.FollowingPosts(userID, cursor, limit)
+	}
+
+	postIDs := make([]string, 0, len(page))
+	for _, entry := range page {
+		postIDs = append(postIDs, entry.postID)
+	}
+
+	return b.store.Posts(postIDs)
+}
+
+// materialize builds the timeline of userID from the store when it is not in
+// memory yet. The posts created or deleted while the store is read are
+// applied to the timeline before it is kept, and a timeline whose owner
+// followed or unfollowed someone meanwhile is not kept at all: Timeline then
+// reads the store, until the next read builds it again.
+func (b *PushBuilder) materialize(userID string) error {
+	b.mu.Lock()
+	for {
+		if element, ok := b.timelines[userID]; ok {
+			b.recent.MoveToFront(element)
+			b.mu.Unlock()
+			return nil
+		}
+
+		build, ok := b.building[userID]
+		if !ok {
+			break
+		}
+
+		// Another read is building the timeline.
+		b.mu.Unlock()
+		<-build.done
+		b.mu.Lock()
+		if build.stale {
+			b.mu.Unlock()
+			return nil
+		}
+	}
+
+	build := &timelineBuild{done: make(chan struct{})}
+	b.building[userID] = build
+	b.mu.Unlock()
+
+	posts, err := b.store.FollowingPosts(userID, nil, b.maxLen+1)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.building, userID)
+	defer close(build.done)
+
+	if err != nil {
+		// Waiting reads do not keep the timeline either.
+		build.stale = true
+		return err
+	}
+	if build.stale {
+		return nil
+	}
+
+	t := &timeline{userID: userID, entries: make([]timelineEntry, 0, len(posts))}
+	if len(posts) > b.maxLen {
+		posts = posts[:b.maxLen]
+		t.truncated = true
+	}
+	for _, post := range posts {
+		t.entries = append(t.entries, timelineEntry{postID: post.ID, createdAt: post.CreatedAt})
+	}
+
+	for _, change := range build.changes {
+		if change.removed {
+			t.remove(change.entry.postID)
+		} else {
+			t.insert(change.entry, b.maxLen)
+		}
+	}
+
+	b.timelines[userID] = b.recent.PushFront(t)
+	for b.recent.Len() > b.maxTimelines {
+		oldest := b.recent.Back()
+		b.recent.Remove(oldest)
+		delete(b.timelines, oldest.Value.(*timeline).userID)
+	}
+
+	return nil
+}
+
+func (t *timeline) insert(entry timelineEntry, maxLen int) {
+	i := sort.Search(len(t.entries), func(i int) bool {
+		return !t.entries[i].before(entry)
+	})
+	if i < len(t.entries) && t.entries[i].postID == entry.postID {
+		return
+	}
+
+	t.entries = append(t.entries, timelineEntry{})
+	copy(t.entries[i+1:], t.entries[i:])
+	t.entries[i] = entry
+
+	if len(t.entries) > maxLen {
+		t.entries = t.entries[:maxLen]
+		t.truncated = true
+	}
+}
+
+func (t *timeline) remove(postID string) {
+	for i, entry := range t.entries {
+		if entry.postID == postID {
+			t.entries = append(t.entries[:i], t.entries[i+1:]...)
+			return
+		}
+	}
+}
+
+// after returns up to limit entries that come after the cursor.
+func (t *timeline) after(cursor *utils.Cursor, limit int) []timelineEntry {
+	start := 0
+	if cursor != nil {
+		cursorEntry := timelineEntry{postID: cursor.ID, createdAt: cursor.Time}
+		start = sort.Search(len(t.entries), func(i int) bool {
+			return cursorEntry.before(t.entries[i])
+		})
+	}
+
+	end := start + limit
+	if end > len(t.entries) {
+		end = len(t.entries)
+	}
+
+	page := make([]timelineEntry, end-start)
+	copy(page, t.entries[start:end])
+	return page
+}
@@ -0,0 +1,46 @@
+package feed
+
+import (
+	"social_api/models"
+	"social_api/utils"
+)
+
+const followersPageLimit = 1000
+
+// DBStore reads the followers graph and the posts from the database.
+type DBStore struct{}
+
+// Followers walks every page of the followers of authorID, since fan-out has
+// to reach all of them.
+func (DBStore) Followers(authorID string) ([]string, error) {
+	followerIDs := make([]string, 0)
+	page := utils.Page{Limit: followersPageLimit}
+
+	for {
+		followers, nextCursor, err := utils.GetFollowers(authorID, page)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, follower := range followers {
+			followerIDs = append(followerIDs, follower.ID)
+		}
+
+		if nextCursor == nil {
+			return followerIDs, nil
+		}
+
+		page.Cursor, err = utils.DecodeCursor(*nextCursor)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (DBStore) FollowingPosts(userID string, cursor *utils.Cursor, limit int) ([]models.Post, error) {
+	return utils.GetFeed(userID, cursor, limit)
+}
+
+func (DBStore) Posts(postIDs []string) ([]models.Post, error) {
+	return utils.GetPostsByIds(postIDs)
+}
@@ -0,0 +1,126 @@
+package imaging
+
+import (
+	"encoding/binary"
+	"image"
+)
+
+const exifOrientationTag = 0x0112
+
+// jpegOrientation returns the EXIF orientation of a JPEG file, from 1 to 8,
+// or 1 when the file is not a JPEG or carries no orientation.
+func jpegOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+
+	offset := 2
+	for offset+4 <= len(data) {
+		if data[offset] != 0xFF {
+			return 1
+		}
+
+		marker := data[offset+1]
+		// The metadata segments all come before the start of scan.
+		if marker == 0xDA || marker == 0xD9 {
+			return 1
+		}
+
+		length := int(binary.BigEndian.Uint16(data[offset+2:]))
+		if length < 2 || offset+2+length > len(data) {
+			return 1
+		}
+
+		segment := data[offset+4 : offset+2+length]
+		if marker == 0xE1 && len(segment) > 6 && string(segment[:6]) == "Exif\x00\x00" {
+			return tiffOrientation(segment[6:])
+		}
+
+		offset += 2 + length
+	}
+
+	return 1
+}
+
+// tiffOrientation reads the orientation tag from the first IFD of the TIFF
+// structure inside an EXIF segment.
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 1
+	}
+
+	entries := int(order.Uint16(tiff[ifd:]))
+	for i := 0; i < entries; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+
+		if order.Uint16(tiff[entry:]) == exifOrientationTag {
+			orientation := int(order.Uint16(tiff[entry+8:]))
+			if orientation < 1 || orientation > 8 {
+				return 1
+			}
+			return orientation
+		}
+	}
+
+	return 1
+}
+
+// applyOrientation turns img so it shows upright for the given EXIF orientation.
+func applyOrientation(img image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+
+	// Orientations 5 to 8 swap the width and the height.
+	destW, destH := w, h
+	if orientation >= 5 {
+		destW, destH = h, w
+	}
+
+	dest := image.NewRGBA(image.Rect(0, 0, destW, destH))
+	for y := 0; y < destH; y++ {
+		for x := 0; x < destW; x++ {
+			var srcX, srcY int
+			switch orientation {
+			case 2: // Mirrored horizontally.
+				srcX, srcY = w-1-x, y
+			case 3: // Rotated 180°.
+				srcX, srcY = w-1-x, h-1-y
+			case 4: // Mirrored vertically.
+				srcX, srcY = x, h-1-y
+			case 5: // Mirrored along the top-left to bottom-right diagonal.
+				srcX, srcY = y, x
+			case 6: // Needs a 90° clockwise rotation.
+				srcX, srcY = y, h-1-x
+			case 7: // Mirrored along the top-right to bottom-left diagonal.
+				srcX, srcY = w-1-y, h-1-x
+			case 8: // Needs a 90° counterclockwise rotation.
+				srcX, srcY = w-1-y, x
+			}
+			dest.Set(x, y, img.At(bounds.Min.X+srcX, bounds.Min.Y+srcY))
+		}
+	}
+
+	return dest
+}
@@ -0,0 +1,177 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+
+	xdraw "golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// MaxPixels bounds the size of the images that are decoded, so a small file
+// cannot expand into a huge bitmap in memory.
+const MaxPixels = 50_000_000
+
+var (
+	ErrUnsupportedImage = errors.New("Unsupported image format")
+	ErrImageTooLarge    = errors.New("Image dimensions are too large")
+)
+
+// Decode reads a JPEG, PNG, GIF or WebP image. Only the first frame of
+// animated images is kept. The EXIF orientation of JPEG images is applied to
+// the pixels, since the metadata that carried it is dropped once the image is
+// encoded again.
+func Decode(r io.Reader) (image.Image, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		if errors.Is(err, image.ErrFormat) {
+			return nil, ErrUnsupportedImage
+		}
+		return nil, err
+	}
+
+	if config.Width*config.Height > MaxPixels {
+		return nil, ErrImageTooLarge
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	return applyOrientation(img, jpegOrientation(data)), nil
+}
+
+// SquareThumbnail crops the biggest centered square out of img and scales it
+// to size x size pixels. Transparent areas are filled with white.
+func SquareThumbnail(img image.Image, size int) image.Image {
+	bounds := img.Bounds()
+	side := bounds.Dx()
+	if bounds.Dy() < side {
+		side = bounds.Dy()
+	}
+
+	x0 := bounds.Min.X + (bounds.Dx()-side)/2
+	y0 := bounds.Min.Y + (bounds.Dy()-side)/2
+	crop := image.Rect(x0, y0, x0+side, y0+side)
+
+	thumbnail := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.Draw(thumbnail, thumbnail.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	xdraw.CatmullRom.Scale(thumbnail, thumbnail.Bounds(), img, crop, xdraw.Over, nil)
+
+	return thumbnail
+}
+
+// Fit scales img down, keeping its aspect ratio, so that neither side is
+// longer than size. Images that already fit are returned as they are.
+func Fit(img image.Image, size int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= size && height <= size {
+		return img
+	}
+
+	if width >= height {
+		height = max(1, height*size/width)
+		width = size
+	} else {
+		width = max(1, width*size/height)
+		height = size
+	}
+
+	scaled := image.NewRGBA(image.Rect(0, 0, width, height))
+	xdraw.CatmullRom.Scale(scaled, scaled.Bounds(), img, bounds, xdraw.Src, nil)
+
+	return scaled
+}
+
+func EncodeJPEG(w io.Writer, img image.Image) error {
+	return jpeg.Encode(w, img, &jpeg.Options{Quality: 90})
+}
+
+// Encode writes img in a format every browser can show: JPEG for opaque
+// images and PNG for the ones with transparency. Only the pixels are written,
+// so no metadata of the original file survives. It returns the content type
+// and the file extension of the chosen format.
+func Encode(w io.Writer, img image.Image) (string, string, error) {
+	if isOpaque(img) {
+		return "image/jpeg", ".jpg", EncodeJPEG(w, img)
+	}
+
+	encoder := png.Encoder{CompressionLevel: png.BestCompression}
+	return "image/png", ".png", encoder.Encode(w, img)
+}
+
+func isOpaque(img image.Image) bool {
+	if opaque, ok := img.(interface{ Opaque() bool }); ok {
+		return opaque.Opaque()
+	}
+
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if _, _, _, a := img.At(x, y).RGBA(); a != 0xffff {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// VariantSizes are the longest sides, in pixels, of the versions every
+// uploaded image is stored in.
+var VariantSizes = []int{64, 256, 1024}
+
+// Variant is img scaled down and encoded by Encode.
+type Variant struct {
+	Width       int
+	Height      int
+	ContentType string
+	Extension   string
+	Data        []byte
+}
+
+// Variants returns img scaled to fit each of sizes, smallest first. Images
+// are never scaled up, so a small image yields fewer variants.
+func Variants(img image.Image, sizes []int) ([]Variant, error) {
+	variants := make([]Variant, 0, len(sizes))
+	seen := make(map[image.Point]bool, len(sizes))
+
+	for _, size := range sizes {
+		scaled := Fit(img, size)
+		dimensions := image.Pt(scaled.Bounds().Dx(), scaled.Bounds().Dy())
+		if seen[dimensions] {
+			continue
+		}
+		seen[dimensions] = true
+
+		var buf bytes.Buffer
+		contentType, extension, err := Encode(&buf, scaled)
+		if err != nil {
+			return nil, err
+		}
+
+		variants = append(variants, Variant{
+			Width:       dimensions.X,
+			Height:      dimensions.Y,
+			ContentType: contentType,
+			Extension:   extension,
+			Data:        buf.Bytes(),
+		})
+	}
+
+	return variants, nil
+}
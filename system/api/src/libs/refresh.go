@@ -0,0 +1,12 @@
+package libs
+
+import "time"
+
+const (
+	// RefreshTokenLifetime is how long a refresh token can wait before being
+	// used. Every use replaces it with a new one.
+	RefreshTokenLifetime = 7 * 24 * time.Hour
+	// SessionLifetime is how long a chain of refresh tokens lasts from the
+	// login, however often it is refreshed.
+	SessionLifetime = 30 * 24 * time.Hour
+)
@@ -0,0 +1,31 @@
+package libs
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+)
+
+// PasswordResetLifetime is how long the link to reset a password works.
+const PasswordResetLifetime = time.Hour
+
+// GenerateSecretToken returns a new random token, like a refresh token or the
+// token of a password reset link, along with the hash that is stored in its
+// place.
+func GenerateSecretToken() (string, string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", err
+	}
+
+	token := base64.RawURLEncoding.EncodeToString(raw)
+	return token, HashSecretToken(token), nil
+}
+
+// HashSecretToken returns the hash a secret token is stored and looked up by.
+func HashSecretToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,126 @@
+// Package lockout slows down password guessing. It counts the failed logins
+// of each account and of each IP, makes them wait longer after each failure
+// and locks them out for a while after too many.
+package lockout
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Tracker counts the attempts of keys, like an account or an IP, and tells
+// how long each one has to wait before its next attempt. An attempt counts as
+// failed from the moment it starts, so that attempts made at the same time
+// cannot all get in before the first of them fails.
+type Tracker interface {
+	// Wait returns how long key has to wait before its next attempt, zero when
+	// it can try now.
+	Wait(key string) time.Duration
+	// Try starts an attempt of key and counts it as failed. When key has to
+	// wait, it returns how long and nothing is counted.
+	Try(key string) time.Duration
+	// Release takes back an attempt of key that succeeded, and keeps its
+	// previous failures.
+	Release(key string)
+	// Reset forgets every attempt of key, once one succeeded.
+	Reset(key string)
+}
+
+// Policy is how a tracker responds to failed attempts.
+type Policy struct {
+	// FreeAttempts is how many attempts can fail before any wait.
+	FreeAttempts int
+	// BaseDelay is the wait after the first failure beyond FreeAttempts. It
+	// doubles with every failure after it, up to MaxDelay.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// LockoutAttempts is how many failures lock the key out for
+	// LockoutDuration. Every failure after it locks it out again. 0 means
+	// never.
+	LockoutAttempts int
+	LockoutDuration time.Duration
+	// Window is how long after its last failure the failures of a key are
+	// forgotten.
+	Window time.Duration
+}
+
+// Delay is the wait after failures failed attempts.
+func (p Policy) Delay(failures int) time.Duration {
+	if p.LockoutAttempts > 0 && failures >= p.LockoutAttempts {
+		return p.LockoutDuration
+	}
+	if failures <= p.FreeAttempts {
+		return 0
+	}
+
+	delay := p.BaseDelay
+	for i := p.FreeAttempts + 1; i < failures && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	return delay
+}
+
+// DefaultAccountPolicy is the policy of each username or email.
+var DefaultAccountPolicy = Policy{
+	FreeAttempts:    3,
+	BaseDelay:       time.Second,
+	MaxDelay:        30 * time.Second,
+	LockoutAttempts: 10,
+	LockoutDuration: 15 * time.Minute,
+	Window:          time.Hour,
+}
+
+// DefaultIPPolicy is the policy of each IP. It is looser than the one of
+// accounts, since many users can share an IP.
+var DefaultIPPolicy = Policy{
+	FreeAttempts:    10,
+	BaseDelay:       time.Second,
+	MaxDelay:        30 * time.Second,
+	LockoutAttempts: 50,
+	LockoutDuration: 15 * time.Minute,
+	Window:          time.Hour,
+}
+
+// Accounts tracks the logins of each username or email, and IPs those of
+// each IP.
+var (
+	Accounts Tracker = NewMemoryTracker(DefaultAccountPolicy)
+	IPs      Tracker = NewMemoryTracker(DefaultIPPolicy)
+)
+
+// Init sets up Accounts and IPs with the default policies, changed by
+// LOGIN_LOCKOUT_ATTEMPTS, LOGIN_IP_LOCKOUT_ATTEMPTS and
+// LOGIN_LOCKOUT_DURATION when they are set.
+func Init() error {
+	accounts, ips := DefaultAccountPolicy, DefaultIPPolicy
+
+	for name, value := range map[string]*int{
+		"LOGIN_LOCKOUT_ATTEMPTS":    &accounts.LockoutAttempts,
+		"LOGIN_IP_LOCKOUT_ATTEMPTS": &ips.LockoutAttempts,
+	} {
+		if raw := os.Getenv(name); raw != "" {
+			n, err := strconv.Atoi(raw)
+			if err != nil || n < 0 {
+				return fmt.Errorf("invalid %s: %q", name, raw)
+			}
+			*value = n
+		}
+	}
+
+	if raw := os.Getenv("LOGIN_LOCKOUT_DURATION"); raw != "" {
+		d, err := time.ParseDuration(raw)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("invalid LOGIN_LOCKOUT_DURATION: %q", raw)
+		}
+		accounts.LockoutDuration, ips.LockoutDuration = d, d
+	}
+
+	Accounts = NewMemoryTracker(accounts)
+	IPs = NewMemoryTracker(ips)
+	return nil
+}
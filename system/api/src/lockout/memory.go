@@ -0,0 +1,115 @@
+package lockout
+
+import (
+	"sync"
+	"time"
+)
+
+// failures are the failed attempts of a key, along with the ones in
+// progress.
+type failures struct {
+	count int
+	last  time.Time
+	until time.Time
+}
+
+// MemoryTracker keeps the failed attempts in memory. They are lost when the
+// API restarts and are not shared between instances.
+type MemoryTracker struct {
+	policy Policy
+	// Now returns the current time. Tests replace it.
+	Now func() time.Time
+
+	mu        sync.Mutex
+	keys      map[string]*failures
+	lastSweep time.Time
+}
+
+// NewMemoryTracker returns a tracker without failures that follows policy.
+func NewMemoryTracker(policy Policy) *MemoryTracker {
+	return &MemoryTracker{
+		policy: policy,
+		Now:    time.Now,
+		keys:   make(map[string]*failures),
+	}
+}
+
+func (t *MemoryTracker) Wait(key string) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	f := t.keys[key]
+	if f == nil {
+		return 0
+	}
+	if wait := f.until.Sub(t.Now()); wait > 0 {
+		return wait
+	}
+	return 0
+}
+
+func (t *MemoryTracker) Try(key string) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.Now()
+	t.sweep(now)
+
+	f := t.keys[key]
+	if f == nil || t.expired(f, now) {
+		f = &failures{}
+		t.keys[key] = f
+	}
+	if wait := f.until.Sub(now); wait > 0 {
+		return wait
+	}
+
+	f.count++
+	f.last = now
+	f.until = now.Add(t.policy.Delay(f.count))
+	return 0
+}
+
+func (t *MemoryTracker) Release(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	f := t.keys[key]
+	if f == nil {
+		return
+	}
+
+	f.count--
+	if f.count <= 0 {
+		delete(t.keys, key)
+		return
+	}
+	f.until = f.last.Add(t.policy.Delay(f.count))
+}
+
+func (t *MemoryTracker) Reset(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.keys, key)
+}
+
+// expired reports whether f is old enough to be forgotten.
+func (t *MemoryTracker) expired(f *failures, now time.Time) bool {
+	return now.Sub(f.last) > t.policy.Window && !now.Before(f.until)
+}
+
+// sweep forgets the failures that expired, at most once per window, so keys
+// that never come back do not pile up.
+func (t *MemoryTracker) sweep(now time.Time) {
+	if now.Sub(t.lastSweep) < t.policy.Window {
+		return
+	}
+	t.lastSweep = now
+
+	for key, f := range t.keys {
+		if t.expired(f, now) {
+			delete(t.keys, key)
+		}
+	}
+}
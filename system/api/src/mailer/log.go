@@ -0,0 +1,40 @@
+package mailer
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// LogMailer stands in for a real mailer in development and tests. Each email
+// is written to its own .eml file in Dir, or to the log when Dir is empty.
+type LogMailer struct {
+	Dir  string
+	From string
+
+	mu    sync.Mutex
+	count int
+}
+
+func (m *LogMailer) Send(message Message) error {
+	content := format(m.From, message)
+
+	if m.Dir == "" {
+		log.Printf("mail to %s:\n%s", message.To, content)
+		return nil
+	}
+
+	if err := os.MkdirAll(m.Dir, 0o755); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	m.count++
+	name := fmt.Sprintf("%s-%03d.eml", time.Now().UTC().Format("20060102T150405"), m.count)
+	m.mu.Unlock()
+
+	return os.WriteFile(filepath.Join(m.Dir, name), content, 0o644)
+}
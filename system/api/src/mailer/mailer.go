@@ -0,0 +1,71 @@
+package mailer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Message is a plain text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers emails to users.
+type Mailer interface {
+	Send(message Message) error
+}
+
+var Default Mailer
+
+var ErrUnknownMailer = errors.New("Unknown mailer")
+
+// Init sets Default to the implementation named by the MAILER environment
+// variable. Outside of production the log mailer keeps emails on disk instead
+// of sending them.
+func Init() error {
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = "no-reply@localhost"
+	}
+
+	switch name := os.Getenv("MAILER"); name {
+	case "", "log":
+		Default = &LogMailer{Dir: os.Getenv("MAIL_DIR"), From: from}
+		return nil
+	case "smtp":
+		port, err := strconv.Atoi(os.Getenv("SMTP_PORT"))
+		if err != nil {
+			return fmt.Errorf("invalid SMTP_PORT: %w", err)
+		}
+
+		Default = &SMTPMailer{
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     port,
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     from,
+		}
+		return nil
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownMailer, name)
+	}
+}
+
+// headerValue drops line breaks so that a value cannot add headers.
+var headerValue = strings.NewReplacer("\r", "", "\n", "")
+
+// format renders message as an RFC 5322 email sent by from.
+func format(from string, message Message) []byte {
+	return []byte("From: " + headerValue.Replace(from) + "\r\n" +
+		"To: " + headerValue.Replace(message.To) + "\r\n" +
+		"Subject: " + headerValue.Replace(message.Subject) + "\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: text/plain; charset=UTF-8\r\n" +
+		"\r\n" +
+		message.Body + "\r\n")
+}
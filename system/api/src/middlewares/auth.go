@@ -0,0 +1,97 @@
+package middlewares
+
+import (
+	"errors"
+
+	"social_api/models"
+	"social_api/tokens"
+	"social_api/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// authLocalsKey is where RequireAuth and OptionalAuth keep the claims of the
+// session token in c.Locals.
+const authLocalsKey = "auth"
+
+// RequireAuth only lets through requests whose session token is valid and
+// belongs to a session that is still open. The claims are kept for handlers,
+// which read them with AuthClaims and AuthUserID.
+//
+// Expired tokens are refused rather than renewed: clients trade their refresh
+// token for a new one at /api/token/refresh.
+func RequireAuth(c *fiber.Ctx) error {
+	if c.Get("session") == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Missing authorization header."})
+	}
+
+	return authenticate(c)
+}
+
+// OptionalAuth works like RequireAuth but also lets through requests without
+// a session token, for which AuthClaims returns nil. A token that is sent is
+// still required to be valid.
+func OptionalAuth(c *fiber.Ctx) error {
+	if c.Get("session") == "" {
+		return c.Next()
+	}
+
+	return authenticate(c)
+}
+
+// RequireRole only lets through requests made by users with at least role,
+// as carried by their session token. It goes after RequireAuth. A new role
+// applies once the user gets a new access token, which takes at most
+// tokens.AccessTokenLifetime.
+func RequireRole(role string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		claims := AuthClaims(c)
+		if claims == nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Missing authorization header."})
+		}
+
+		if !models.RoleAtLeast(claims.Role, role) {
+			utils.HandleError(c, utils.ErrUnauthorized, fiber.StatusForbidden)
+			return nil
+		}
+
+		return c.Next()
+	}
+}
+
+// AuthClaims returns the claims of the session token of the request, or nil
+// when it was not authenticated.
+func AuthClaims(c *fiber.Ctx) *tokens.AccessClaims {
+	claims, _ := c.Locals(authLocalsKey).(*tokens.AccessClaims)
+	return claims
+}
+
+// AuthUserID returns the ID of the user that made the request, or an empty
+// string when it was not authenticated.
+func AuthUserID(c *fiber.Ctx) string {
+	if claims := AuthClaims(c); claims != nil {
+		return claims.UserID
+	}
+	return ""
+}
+
+func authenticate(c *fiber.Ctx) error {
+	claims, err := tokens.ParseAccessToken(c.Get("session"))
+	if err != nil {
+		if errors.Is(err, tokens.ErrExpired) {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Token expired"})
+		}
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token"})
+	}
+
+	active, err := utils.TouchSession(claims.SessionID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": utils.ErrFindSession.Error()})
+	}
+	if !active {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": utils.ErrSessionRevoked.Error()})
+	}
+
+	c.Locals(authLocalsKey, claims)
+	return c.Next()
+}
@@ -0,0 +1,44 @@
+package middlewares
+
+import (
+	"social_api/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// DefaultBodyLimit is the most a request body can weigh on the routes that
+// do not raise it. It is also the BodyLimit of the app: bigger bodies are
+// streamed rather than read in memory, so that the routes that accept them
+// can store them as they arrive.
+const DefaultBodyLimit = 4 << 20
+
+// LimitBody refuses the requests whose body is bigger than DefaultBodyLimit,
+// or than the limit of their path in limits. It goes before every route, and
+// relies on Content-Length since the body is not read yet, so requests
+// without one are refused when they send a body.
+func LimitBody(limits map[string]int) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		limit := DefaultBodyLimit
+		if pathLimit, ok := limits[c.Path()]; ok {
+			limit = pathLimit
+		}
+
+		length := c.Request().Header.ContentLength()
+		if length < 0 || length > limit {
+			// The body is left unread, so the connection cannot carry another
+			// request.
+			c.Context().SetConnectionClose()
+		}
+
+		if length < 0 {
+			utils.HandleError(c, utils.ErrLengthRequired, fiber.StatusLengthRequired)
+			return nil
+		}
+		if length > limit {
+			utils.HandleError(c, utils.ErrBodyTooLarge, fiber.StatusRequestEntityTooLarge)
+			return nil
+		}
+
+		return c.Next()
+	}
+}
@@ -0,0 +1,86 @@
+package middlewares
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"social_api/ratelimit"
+	"social_api/tokens"
+	"social_api/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RateLimit limits the requests of each client following policy, with the
+// buckets of ratelimit.Default. Clients are told apart by their user when
+// they send a valid session token, and by their IP otherwise. It goes before
+// RequireAuth, so that requests are limited before any other work.
+//
+// The response carries the RateLimit-Limit, RateLimit-Remaining and
+// RateLimit-Reset headers of the tightest policy the request went through.
+// Refused requests get a 429 with Retry-After.
+func RateLimit(policy *ratelimit.Policy) fiber.Handler {
+	return rateLimit(policy, rateLimitKey)
+}
+
+// RateLimitByIP works like RateLimit but always tells clients apart by their
+// IP. It goes on the routes that do not need a session, like the ones that
+// check credentials, where the token of any account would otherwise earn a
+// bucket of its own.
+func RateLimitByIP(policy *ratelimit.Policy) fiber.Handler {
+	return rateLimit(policy, ipRateLimitKey)
+}
+
+func rateLimit(policy *ratelimit.Policy, keyOf func(c *fiber.Ctx) string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if policy.Limit == 0 {
+			return c.Next()
+		}
+
+		result, err := ratelimit.Default.Take(policy.Name+":"+keyOf(c), *policy)
+		if err != nil {
+			// Requests are let through rather than refused when the store
+			// fails.
+			fmt.Println("Error taking rate limit token:", err)
+			return c.Next()
+		}
+
+		if remaining, err := strconv.Atoi(c.GetRespHeader("RateLimit-Remaining")); err != nil || result.Remaining < remaining || !result.Allowed {
+			c.Set("RateLimit-Limit", strconv.Itoa(policy.Limit))
+			c.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			c.Set("RateLimit-Reset", ceilSeconds(result.Reset))
+			c.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", policy.Limit, int(policy.Period.Seconds())))
+		}
+
+		if !result.Allowed {
+			c.Set(fiber.HeaderRetryAfter, ceilSeconds(result.RetryAfter))
+			utils.HandleError(c, utils.ErrTooManyRequests, http.StatusTooManyRequests)
+			return nil
+		}
+
+		return c.Next()
+	}
+}
+
+// rateLimitKey identifies the client of the request. The session is not
+// checked against the database: a logged out token still only spends the
+// tokens of its own user.
+func rateLimitKey(c *fiber.Ctx) string {
+	if token := c.Get("session"); token != "" {
+		if claims, err := tokens.ParseAccessToken(token); err == nil {
+			return "user:" + claims.UserID
+		}
+	}
+	return ipRateLimitKey(c)
+}
+
+func ipRateLimitKey(c *fiber.Ctx) string {
+	return "ip:" + c.IP()
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
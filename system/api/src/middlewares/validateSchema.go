@@ -3,6 +3,8 @@ package middlewares
 import (
 	"encoding/json"
 	"net/http"
+	"reflect"
+	"social_api/passwords"
 	"social_api/schemas"
 	"social_api/utils"
 	"strings"
@@ -69,6 +71,8 @@ func ValidateRegisterSchema(c *fiber.Ctx) error {
 					errorMessages[fieldName] = "This field must be at most " + vErr.Param() + " characters."
 				case "email":
 					errorMessages[fieldName] = "This field must be a valid email address."
+				case "password":
+					errorMessages[fieldName] = passwordError(vErr)
 				default:
 					errorMessages[fieldName] = "This field is invalid."
 				}
@@ -144,3 +148,140 @@ func ValidateLoginSchema(c *fiber.Ctx) error {
 
 	return c.Next()
 }
+
+func ValidateLoginTwoFactorSchema(c *fiber.Ctx) error {
+	var requestBody schemas.LoginTwoFactorRequest
+	return validateRequestBody(c, &requestBody)
+}
+
+func ValidateConfirmTwoFactorSchema(c *fiber.Ctx) error {
+	var requestBody schemas.ConfirmTwoFactorRequest
+	return validateRequestBody(c, &requestBody)
+}
+
+func ValidateDisableTwoFactorSchema(c *fiber.Ctx) error {
+	var requestBody schemas.DisableTwoFactorRequest
+	return validateRequestBody(c, &requestBody)
+}
+
+func ValidatePasskeyRegistrationSchema(c *fiber.Ctx) error {
+	var requestBody schemas.PasskeyRegistrationRequest
+	return validateRequestBody(c, &requestBody)
+}
+
+func ValidatePasskeyLoginSchema(c *fiber.Ctx) error {
+	var requestBody schemas.PasskeyLoginRequest
+	return validateRequestBody(c, &requestBody)
+}
+
+func ValidateRefreshTokenSchema(c *fiber.Ctx) error {
+	var requestBody schemas.RefreshTokenRequest
+	return validateRequestBody(c, &requestBody)
+}
+
+func ValidateForgotPasswordSchema(c *fiber.Ctx) error {
+	var requestBody schemas.ForgotPasswordRequest
+	return validateRequestBody(c, &requestBody)
+}
+
+func ValidateResetPasswordSchema(c *fiber.Ctx) error {
+	var requestBody schemas.ResetPasswordRequest
+	return validateRequestBody(c, &requestBody)
+}
+
+func ValidateUpdatePasswordSchema(c *fiber.Ctx) error {
+	var requestBody schemas.UpdatePasswordRequest
+	return validateRequestBody(c, &requestBody)
+}
+
+func ValidateUpdateDescriptionSchema(c *fiber.Ctx) error {
+	var requestBody schemas.UpdateDescriptionRequest
+	return validateRequestBody(c, &requestBody)
+}
+
+func ValidateCreatePostSchema(c *fiber.Ctx) error {
+	var requestBody schemas.CreatePostRequest
+	return validateRequestBody(c, &requestBody)
+}
+
+func ValidateUpdatePostSchema(c *fiber.Ctx) error {
+	var requestBody schemas.UpdatePostRequest
+	return validateRequestBody(c, &requestBody)
+}
+
+func ValidateCreateResponseSchema(c *fiber.Ctx) error {
+	var requestBody schemas.CreateResponseRequest
+	return validateRequestBody(c, &requestBody)
+}
+
+func ValidateUpdateResponseSchema(c *fiber.Ctx) error {
+	var requestBody schemas.UpdateResponseRequest
+	return validateRequestBody(c, &requestBody)
+}
+
+// validateRequestBody decodes the body into requestBody and checks it against
+// its schema, answering with a 400 when either step fails.
+func validateRequestBody(c *fiber.Ctx, requestBody interface{}) error {
+	if err := json.Unmarshal(c.Body(), requestBody); err != nil {
+		if strings.Contains(err.Error(), "invalid character") || strings.Contains(err.Error(), "unexpected end of JSON input") {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid JSON format",
+			})
+		}
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if err := schemas.Validate(requestBody); err != nil {
+		if validationErr, ok := err.(validator.ValidationErrors); ok {
+			errorMessages := make(map[string]string)
+
+			for _, vErr := range validationErr {
+				fieldName := vErr.Field()
+
+				switch vErr.Tag() {
+				case "required":
+					errorMessages[fieldName] = "This field is required."
+				case "min":
+					errorMessages[fieldName] = "This field must be at least " + vErr.Param() + " " + lengthUnit(vErr) + "."
+				case "max":
+					errorMessages[fieldName] = "This field must be at most " + vErr.Param() + " " + lengthUnit(vErr) + "."
+				case "email":
+					errorMessages[fieldName] = "This field must be a valid email address."
+				case "url":
+					errorMessages[fieldName] = "This field must be a valid URL."
+				case "uuid":
+					errorMessages[fieldName] = "This field must be a valid UUID."
+				case "password":
+					errorMessages[fieldName] = passwordError(vErr)
+				default:
+					errorMessages[fieldName] = "This field is invalid."
+				}
+			}
+
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"errors": errorMessages,
+			})
+		}
+		return err
+	}
+
+	return c.Next()
+}
+
+func lengthUnit(vErr validator.FieldError) string {
+	if vErr.Kind() == reflect.Slice {
+		return "items"
+	}
+	return "characters"
+}
+
+// passwordError tells what the password policy found wrong with the field.
+func passwordError(vErr validator.FieldError) string {
+	value, _ := vErr.Value().(string)
+	if err := passwords.Default.Check(value); err != nil {
+		return err.Error()
+	}
+	return "This field is invalid."
+}
@@ -0,0 +1,31 @@
+package middlewares
+
+import (
+	"net/http"
+	"os"
+
+	"social_api/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RequireVerifiedEmail refuses requests from users that have not verified
+// their email yet when REQUIRE_VERIFIED_EMAIL is "true", and lets every
+// request through otherwise. It goes after RequireAuth.
+func RequireVerifiedEmail(c *fiber.Ctx) error {
+	if os.Getenv("REQUIRE_VERIFIED_EMAIL") != "true" {
+		return c.Next()
+	}
+
+	verified, err := utils.IsEmailVerified(AuthUserID(c))
+	if err != nil {
+		utils.HandleError(c, utils.ErrFindUser, http.StatusInternalServerError)
+		return nil
+	}
+	if !verified {
+		utils.HandleError(c, utils.ErrEmailNotVerified, http.StatusForbidden)
+		return nil
+	}
+
+	return c.Next()
+}
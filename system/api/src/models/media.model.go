@@ -0,0 +1,18 @@
+package models
+
+// Image is an uploaded image as attached to posts and responses. URL points
+// at its biggest variant, and Blurhash is a placeholder that clients can draw
+// while the image loads.
+type Image struct {
+	URL      string         `json:"url" validate:"required,url"`
+	Width    int            `json:"width" validate:"gt=0"`
+	Height   int            `json:"height" validate:"gt=0"`
+	Blurhash string         `json:"blurhash" validate:"max=100"`
+	Variants []ImageVariant `json:"variants" validate:"max=5,dive"`
+}
+
+type ImageVariant struct {
+	URL    string `json:"url" validate:"required,url"`
+	Width  int    `json:"width" validate:"gt=0"`
+	Height int    `json:"height" validate:"gt=0"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"github.com/go-webauthn/webauthn/webauthn"
+)
+
+// Passkey is a WebAuthn credential a user logs in with instead of their
+// password. ID is the credential ID, base64url encoded.
+type Passkey struct {
+	ID         string              `json:"id"`
+	UserID     string              `json:"-"`
+	Name       string              `json:"name"`
+	BackedUp   bool                `json:"backedUp"`
+	CreatedAt  time.Time           `json:"createdAt"`
+	LastUsedAt *time.Time          `json:"lastUsedAt"`
+	Credential webauthn.Credential `json:"-"`
+}
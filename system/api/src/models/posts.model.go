@@ -1,26 +1,39 @@
 package models
 
 import (
-	"gorm.io/gorm"
+	"time"
 )
 
 type Post struct {
-	gorm.Model
-	AuthorID    uint   `gorm:"foreignKey:AuthorID"`
-	Title       string `gorm:"not null"`
-	Description string `gorm:"not null"`
-	Likes       int    `gorm:"default:0"`
-	Responses   []Response
-	Images      []string
-	Videos      []string
+	ID          string     `json:"id"`
+	AuthorID    string     `json:"authorId"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Likes       int        `json:"likes"`
+	Responses   []Response `json:"responses,omitempty"`
+	Images      []Image    `json:"images"`
+	Videos      []string   `json:"videos"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
 }
 
+// Response is a comment on a post. ParentID is set when it replies to another
+// response of the same post, and Replies is only filled when listing a thread
+// as a tree, along with MoreReplies, the number of replies of the thread that
+// did not fit in it.
 type Response struct {
-	gorm.Model
-	PostID   uint   `gorm:"foreignKey:PostID"`
-	AuthorID uint   `gorm:"foreignKey:AuthorID"`
-	Content  string `gorm:"not null"`
-	Likes    int    `gorm:"default:0"`
-	Images   []string
-	Videos   []string
+	ID        string      `json:"id"`
+	PostID    string      `json:"postId"`
+	ParentID  *string     `json:"parentId"`
+	AuthorID  string      `json:"authorId"`
+	Content   string      `json:"content"`
+	Likes     int         `json:"likes"`
+	Images    []Image     `json:"images"`
+	Videos    []string    `json:"videos"`
+	Deleted   bool        `json:"deleted"`
+	CreatedAt time.Time   `json:"createdAt"`
+	UpdatedAt time.Time   `json:"updatedAt"`
+	Replies   []*Response `json:"replies,omitempty"`
+
+	MoreReplies int `json:"moreReplies,omitempty"`
 }
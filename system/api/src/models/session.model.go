@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+)
+
+// Session is a login of a user on one device. Current is only set when
+// listing the sessions of the user that makes the request.
+type Session struct {
+	ID         string    `json:"id"`
+	UserID     string    `json:"-"`
+	UserAgent  string    `json:"userAgent"`
+	IP         string    `json:"ip"`
+	RequestID  string    `json:"requestId"`
+	Current    bool      `json:"current"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastSeenAt time.Time `json:"lastSeenAt"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+}
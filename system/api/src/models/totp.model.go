@@ -0,0 +1,10 @@
+package models
+
+// TOTP is the authenticator app secret of a user. It only protects logins once
+// Confirmed, after the user proved their app generates the right codes.
+type TOTP struct {
+	UserID       string
+	Secret       string
+	Confirmed    bool
+	LastUsedStep *int64
+}
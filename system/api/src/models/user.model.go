@@ -2,6 +2,7 @@ package models
 
 import (
 	"database/sql"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -9,18 +10,66 @@ import (
 type User struct {
 	gorm.Model
 
-	ID          sql.NullString `json:"id"`
-	Username    string         `json:"username"`
-	FirstName   string         `json:"firstName"`
-	LastName    string         `json:"lastName"`
-	Email       string         `json:"email"`
-	Password    string         `json:"password"`
-	Picture     sql.NullString `json:"picture"`
-	Description sql.NullString `json:"description"`
+	ID            sql.NullString `json:"id"`
+	Username      string         `json:"username"`
+	FirstName     string         `json:"firstName"`
+	LastName      string         `json:"lastName"`
+	Email         string         `json:"email"`
+	EmailVerified bool           `json:"emailVerified"`
+	Password      string         `json:"password"`
+	Picture       sql.NullString `json:"picture"`
+	Description   sql.NullString `json:"description"`
+	Role          string         `json:"role"`
+	// SuspendedAt is when a moderator suspended the user, who cannot log in
+	// until they are unsuspended.
+	SuspendedAt sql.NullTime `json:"suspendedAt"`
+	// PasswordResetRequired is set when an admin forces the user to reset
+	// their password before they can log in again.
+	PasswordResetRequired bool `json:"passwordResetRequired"`
 
 	Posts []Post `gorm:"foreignKey:UserID"`
 }
 
+// The roles of users, from the least to the most privileged. Each role can
+// do everything the ones before it can.
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+var roleRanks = map[string]int{
+	RoleUser:      1,
+	RoleModerator: 2,
+	RoleAdmin:     3,
+}
+
+// RoleAtLeast reports whether role grants every privilege of minimum. Unknown
+// roles grant nothing.
+func RoleAtLeast(role, minimum string) bool {
+	rank, ok := roleRanks[role]
+	return ok && rank >= roleRanks[minimum]
+}
+
+// RoleAbove reports whether role is more privileged than other, which is what
+// it takes to moderate a user of role other.
+func RoleAbove(role, other string) bool {
+	rank, ok := roleRanks[role]
+	return ok && rank > roleRanks[other]
+}
+
+// UserAccount is what moderators see of a user when listing them.
+type UserAccount struct {
+	ID                    string     `json:"id"`
+	Username              string     `json:"username"`
+	Email                 string     `json:"email"`
+	EmailVerified         bool       `json:"emailVerified"`
+	Role                  string     `json:"role"`
+	SuspendedAt           *time.Time `json:"suspendedAt"`
+	PasswordResetRequired bool       `json:"passwordResetRequired"`
+	CreatedAt             time.Time  `json:"createdAt"`
+}
+
 type Followers struct {
 	gorm.Model
 	Followers []User `json:"followers"`
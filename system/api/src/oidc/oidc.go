@@ -0,0 +1,223 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	gooidc "github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+// ErrNonce is returned when the ID token was not issued for the login that
+// exchanged it.
+var ErrNonce = errors.New("the ID token was issued for another login")
+
+// Config is what is needed to log in with a provider. The rest is read from
+// the discovery document of Issuer.
+type Config struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	// Scopes are requested along with openid. They default to email and
+	// profile.
+	Scopes []string
+}
+
+// Provider is an OpenID Connect provider users can log in with.
+type Provider struct {
+	Name     string
+	oauth2   oauth2.Config
+	verifier *gooidc.IDTokenVerifier
+}
+
+// Identity is who the provider says the user is. Subject identifies them
+// for good, while their email may change.
+type Identity struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	GivenName     string
+	FamilyName    string
+	Username      string
+}
+
+// AuthRequest is what has to be kept while the user is away logging in with
+// the provider. State ties their return to this request, Nonce ties the ID
+// token to it, and Verifier is the PKCE code verifier.
+type AuthRequest struct {
+	State    string
+	Nonce    string
+	Verifier string
+}
+
+// NewProvider reads the discovery document of config.Issuer, which has to
+// match the issuer it announces.
+func NewProvider(ctx context.Context, config Config) (*Provider, error) {
+	provider, err := gooidc.NewProvider(ctx, config.Issuer)
+	if err != nil {
+		return nil, err
+	}
+
+	scopes := config.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{"email", "profile"}
+	}
+
+	return &Provider{
+		Name: config.Name,
+		oauth2: oauth2.Config{
+			ClientID:     config.ClientID,
+			ClientSecret: config.ClientSecret,
+			RedirectURL:  config.RedirectURL,
+			Endpoint:     provider.Endpoint(),
+			Scopes:       append([]string{gooidc.ScopeOpenID}, scopes...),
+		},
+		verifier: provider.Verifier(&gooidc.Config{ClientID: config.ClientID}),
+	}, nil
+}
+
+// NewAuthRequest returns a request with new random values.
+func NewAuthRequest() (AuthRequest, error) {
+	state, err := randomString()
+	if err != nil {
+		return AuthRequest{}, err
+	}
+
+	nonce, err := randomString()
+	if err != nil {
+		return AuthRequest{}, err
+	}
+
+	return AuthRequest{State: state, Nonce: nonce, Verifier: oauth2.GenerateVerifier()}, nil
+}
+
+// AuthCodeURL is where to send the user to log in with the provider.
+func (p *Provider) AuthCodeURL(request AuthRequest) string {
+	return p.oauth2.AuthCodeURL(request.State, gooidc.Nonce(request.Nonce), oauth2.S256ChallengeOption(request.Verifier))
+}
+
+// Exchange trades the code the user came back with for their ID token, and
+// returns their identity once the token is verified: its signature, issuer,
+// audience, expiration and nonce.
+func (p *Provider) Exchange(ctx context.Context, request AuthRequest, code string) (*Identity, error) {
+	token, err := p.oauth2.Exchange(ctx, code, oauth2.VerifierOption(request.Verifier))
+	if err != nil {
+		return nil, err
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, errors.New("the token response has no ID token")
+	}
+
+	idToken, err := p.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, err
+	}
+	if idToken.Nonce != request.Nonce {
+		return nil, ErrNonce
+	}
+
+	var claims struct {
+		Email             string      `json:"email"`
+		EmailVerified     interface{} `json:"email_verified"`
+		Name              string      `json:"name"`
+		GivenName         string      `json:"given_name"`
+		FamilyName        string      `json:"family_name"`
+		PreferredUsername string      `json:"preferred_username"`
+	}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, err
+	}
+
+	return &Identity{
+		Provider: p.Name,
+		Subject:  idToken.Subject,
+		Email:    strings.ToLower(strings.TrimSpace(claims.Email)),
+		// Some providers send the flag as a string.
+		EmailVerified: claims.EmailVerified == true || claims.EmailVerified == "true",
+		Name:          claims.Name,
+		GivenName:     claims.GivenName,
+		FamilyName:    claims.FamilyName,
+		Username:      claims.PreferredUsername,
+	}, nil
+}
+
+var providers = make(map[string]*Provider)
+
+// Lookup returns the provider called name, or nil when it is not set up.
+func Lookup(name string) *Provider {
+	return providers[name]
+}
+
+// Register makes provider available to Lookup.
+func Register(provider *Provider) {
+	providers[provider.Name] = provider
+}
+
+var providerName = regexp.MustCompile(`^[a-z0-9]+$`)
+
+// Init sets up the providers named in OIDC_PROVIDERS, a comma separated
+// list. Each one is read from OIDC_<NAME>_ISSUER, OIDC_<NAME>_CLIENT_ID,
+// OIDC_<NAME>_CLIENT_SECRET and the optional OIDC_<NAME>_SCOPES, and users
+// come back from it to APP_BASE_URL/api/login/oidc/<name>/callback.
+func Init() error {
+	baseURL := os.Getenv("APP_BASE_URL")
+	if baseURL == "" {
+		port := os.Getenv("PORT")
+		if port == "" {
+			port = "3000"
+		}
+		baseURL = "http://localhost:" + port
+	}
+	baseURL = strings.TrimSuffix(baseURL, "/")
+
+	for _, name := range strings.Split(os.Getenv("OIDC_PROVIDERS"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if !providerName.MatchString(name) {
+			return fmt.Errorf("invalid OIDC provider name: %q", name)
+		}
+
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		config := Config{
+			Name:         name,
+			Issuer:       os.Getenv(prefix + "ISSUER"),
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			RedirectURL:  baseURL + "/api/login/oidc/" + name + "/callback",
+			Scopes:       strings.Fields(os.Getenv(prefix + "SCOPES")),
+		}
+		if config.Issuer == "" || config.ClientID == "" {
+			return fmt.Errorf("%sISSUER and %sCLIENT_ID are required", prefix, prefix)
+		}
+
+		provider, err := NewProvider(context.Background(), config)
+		if err != nil {
+			return fmt.Errorf("discovering %s: %w", name, err)
+		}
+		Register(provider)
+	}
+
+	return nil
+}
+
+func randomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
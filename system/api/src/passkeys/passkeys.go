@@ -0,0 +1,178 @@
+package passkeys
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+)
+
+// CeremonyLifetime is how long an authenticator has to answer the options of
+// a registration or a login.
+const CeremonyLifetime = 5 * time.Minute
+
+// ErrClonedAuthenticator is returned when the signature counter of a
+// credential went backwards, a sign that its private key was copied.
+var ErrClonedAuthenticator = errors.New("the signature counter of the credential went backwards")
+
+// Default is the relying party every ceremony of the API runs as.
+var Default *webauthn.WebAuthn
+
+// Init sets up Default from WEBAUTHN_ORIGINS, the comma separated origins
+// the API is used from, and WEBAUTHN_RP_ID, the domain passkeys are bound
+// to. The origins default to APP_BASE_URL, and the domain to the host of the
+// first origin.
+func Init() error {
+	var origins []string
+	for _, origin := range strings.Split(os.Getenv("WEBAUTHN_ORIGINS"), ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+	if len(origins) == 0 {
+		origin := os.Getenv("APP_BASE_URL")
+		if origin == "" {
+			port := os.Getenv("PORT")
+			if port == "" {
+				port = "3000"
+			}
+			origin = "http://localhost:" + port
+		}
+		origins = []string{strings.TrimSuffix(origin, "/")}
+	}
+
+	rpID := os.Getenv("WEBAUTHN_RP_ID")
+	if rpID == "" {
+		u, err := url.Parse(origins[0])
+		if err != nil || u.Hostname() == "" {
+			return fmt.Errorf("invalid WebAuthn origin: %q", origins[0])
+		}
+		rpID = u.Hostname()
+	}
+
+	rpName := os.Getenv("WEBAUTHN_RP_NAME")
+	if rpName == "" {
+		rpName = "Social API"
+	}
+
+	w, err := New(rpID, rpName, origins)
+	if err != nil {
+		return err
+	}
+
+	Default = w
+	return nil
+}
+
+// New returns a relying party for rpID that accepts responses from origins.
+// Passkeys must be discoverable and verify the user, by PIN or biometrics,
+// since they log in without a password.
+func New(rpID, rpName string, origins []string) (*webauthn.WebAuthn, error) {
+	timeout := webauthn.TimeoutConfig{
+		Enforce:    true,
+		Timeout:    CeremonyLifetime,
+		TimeoutUVD: CeremonyLifetime,
+	}
+
+	return webauthn.New(&webauthn.Config{
+		RPID:                  rpID,
+		RPDisplayName:         rpName,
+		RPOrigins:             origins,
+		AttestationPreference: protocol.PreferNoAttestation,
+		AuthenticatorSelection: protocol.AuthenticatorSelection{
+			RequireResidentKey: protocol.ResidentKeyRequired(),
+			ResidentKey:        protocol.ResidentKeyRequirementRequired,
+			UserVerification:   protocol.VerificationRequired,
+		},
+		Timeouts: webauthn.TimeoutsConfig{
+			Login:        timeout,
+			Registration: timeout,
+		},
+	})
+}
+
+// User is a user of the API as authenticators see it. Its ID is the user
+// handle stored in their passkeys.
+type User struct {
+	ID          string
+	Name        string
+	DisplayName string
+	Credentials []webauthn.Credential
+}
+
+func (u *User) WebAuthnID() []byte {
+	return []byte(u.ID)
+}
+
+func (u *User) WebAuthnName() string {
+	return u.Name
+}
+
+func (u *User) WebAuthnDisplayName() string {
+	return u.DisplayName
+}
+
+func (u *User) WebAuthnCredentials() []webauthn.Credential {
+	return u.Credentials
+}
+
+func (u *User) WebAuthnIcon() string {
+	return ""
+}
+
+// BeginRegistration returns the options to create a new passkey of user
+// with, and the session to check the answer against. Authenticators that
+// already hold a passkey of user are excluded.
+func BeginRegistration(w *webauthn.WebAuthn, user *User) (*protocol.CredentialCreation, *webauthn.SessionData, error) {
+	exclusions := make([]protocol.CredentialDescriptor, len(user.Credentials))
+	for i, credential := range user.Credentials {
+		exclusions[i] = credential.Descriptor()
+	}
+
+	return w.BeginRegistration(user, webauthn.WithExclusions(exclusions))
+}
+
+// FinishRegistration checks the answer of the authenticator to session and
+// returns the new credential to store.
+func FinishRegistration(w *webauthn.WebAuthn, user *User, session webauthn.SessionData, response *protocol.ParsedCredentialCreationData) (*webauthn.Credential, error) {
+	return w.CreateCredential(user, session, response)
+}
+
+// BeginLogin returns the options to log in with any passkey of the relying
+// party. The authenticator tells which user the passkey belongs to.
+func BeginLogin(w *webauthn.WebAuthn) (*protocol.CredentialAssertion, *webauthn.SessionData, error) {
+	return w.BeginDiscoverableLogin()
+}
+
+// FinishLogin checks the answer of the authenticator to session. lookup
+// finds the user of the passkey from its ID and the user handle it returned.
+// It returns that user and their credential with its new signature counter.
+func FinishLogin(w *webauthn.WebAuthn, session webauthn.SessionData, response *protocol.ParsedCredentialAssertionData, lookup func(credentialID, userHandle []byte) (*User, error)) (*User, *webauthn.Credential, error) {
+	var user *User
+	handler := func(credentialID, userHandle []byte) (webauthn.User, error) {
+		var err error
+		user, err = lookup(credentialID, userHandle)
+		if err != nil {
+			return nil, err
+		}
+		if user == nil {
+			return nil, errors.New("unknown credential")
+		}
+		return user, nil
+	}
+
+	credential, err := w.ValidateDiscoverableLogin(handler, session, response)
+	if err != nil {
+		return nil, nil, err
+	}
+	if credential.Authenticator.CloneWarning {
+		return nil, nil, ErrClonedAuthenticator
+	}
+
+	return user, credential, nil
+}
@@ -0,0 +1,158 @@
+package passwords
+
+import (
+	"bufio"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Policy is what a password must look like to be accepted.
+type Policy struct {
+	MinLength     int
+	MaxLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	// HistorySize is how many of the last passwords of a user, the current one
+	// included, cannot be chosen again.
+	HistorySize int
+}
+
+// MaxLength is the longest password bcrypt can hash, in bytes.
+const MaxLength = 72
+
+// Default is the policy every password of the API is checked against.
+var Default = Policy{
+	MinLength:   8,
+	MaxLength:   MaxLength,
+	HistorySize: 5,
+}
+
+//go:embed breached.txt
+var breachedList string
+
+var breached = make(map[string]bool)
+
+func init() {
+	loadBreached(strings.NewReader(breachedList))
+}
+
+// Init reads the policy from the PASSWORD_* environment variables, keeping
+// the default of those that are not set, and adds the passwords of the file
+// at BREACHED_PASSWORDS_FILE to the breached list.
+func Init() error {
+	policy := Default
+
+	for name, value := range map[string]*int{
+		"PASSWORD_MIN_LENGTH": &policy.MinLength,
+		"PASSWORD_HISTORY":    &policy.HistorySize,
+	} {
+		if raw := os.Getenv(name); raw != "" {
+			n, err := strconv.Atoi(raw)
+			if err != nil || n < 0 {
+				return fmt.Errorf("invalid %s: %q", name, raw)
+			}
+			*value = n
+		}
+	}
+
+	for name, value := range map[string]*bool{
+		"PASSWORD_REQUIRE_UPPER":  &policy.RequireUpper,
+		"PASSWORD_REQUIRE_LOWER":  &policy.RequireLower,
+		"PASSWORD_REQUIRE_DIGIT":  &policy.RequireDigit,
+		"PASSWORD_REQUIRE_SYMBOL": &policy.RequireSymbol,
+	} {
+		if raw := os.Getenv(name); raw != "" {
+			b, err := strconv.ParseBool(raw)
+			if err != nil {
+				return fmt.Errorf("invalid %s: %q", name, raw)
+			}
+			*value = b
+		}
+	}
+
+	if policy.MinLength > policy.MaxLength {
+		return fmt.Errorf("PASSWORD_MIN_LENGTH cannot be over %d", policy.MaxLength)
+	}
+
+	if path := os.Getenv("BREACHED_PASSWORDS_FILE"); path != "" {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		if err := loadBreached(file); err != nil {
+			return err
+		}
+	}
+
+	Default = policy
+	return nil
+}
+
+// Check returns an error telling what is wrong with password, or nil when
+// the policy accepts it.
+func (p Policy) Check(password string) error {
+	if length := len([]rune(password)); length < p.MinLength {
+		return fmt.Errorf("Password must be at least %d characters.", p.MinLength)
+	}
+	if len(password) > p.MaxLength {
+		return fmt.Errorf("Password must be at most %d bytes.", p.MaxLength)
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+
+	switch {
+	case p.RequireUpper && !upper:
+		return errors.New("Password must have an uppercase letter.")
+	case p.RequireLower && !lower:
+		return errors.New("Password must have a lowercase letter.")
+	case p.RequireDigit && !digit:
+		return errors.New("Password must have a digit.")
+	case p.RequireSymbol && !symbol:
+		return errors.New("Password must have a symbol.")
+	}
+
+	if IsBreached(password) {
+		return errors.New("Password is too common, it appears in data breaches.")
+	}
+
+	return nil
+}
+
+// IsBreached reports whether password is in the breached list.
+func IsBreached(password string) bool {
+	return breached[strings.ToLower(password)]
+}
+
+func loadBreached(list io.Reader) error {
+	scanner := bufio.NewScanner(list)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		breached[strings.ToLower(line)] = true
+	}
+	return scanner.Err()
+}
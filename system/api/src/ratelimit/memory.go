@@ -0,0 +1,65 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often MemoryStore forgets the buckets that are full.
+const sweepInterval = time.Minute
+
+type memoryBucket struct {
+	Bucket
+	period time.Duration
+}
+
+// MemoryStore keeps the buckets in memory. They are lost when the API
+// restarts and are not shared between instances.
+type MemoryStore struct {
+	// Now returns the current time. Tests replace it.
+	Now func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*memoryBucket
+	lastSweep time.Time
+}
+
+// NewMemoryStore returns a store where every bucket is full.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		Now:     time.Now,
+		buckets: make(map[string]*memoryBucket),
+	}
+}
+
+func (s *MemoryStore) Take(key string, policy Policy) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.Now()
+	s.sweep(now)
+
+	bucket := s.buckets[key]
+	if bucket == nil {
+		bucket = &memoryBucket{}
+		s.buckets[key] = bucket
+	}
+	bucket.period = policy.Period
+
+	return policy.Take(&bucket.Bucket, now), nil
+}
+
+// sweep forgets the buckets that had the time to fill up again, since they
+// are the same as new ones.
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+
+	for key, bucket := range s.buckets {
+		if now.Sub(bucket.Updated) >= bucket.period {
+			delete(s.buckets, key)
+		}
+	}
+}
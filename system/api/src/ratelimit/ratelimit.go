@@ -0,0 +1,142 @@
+// Package ratelimit limits how many requests each client makes with token
+// buckets: every client has a bucket of Limit tokens per policy, which refills
+// evenly over Period, and each request takes a token from it.
+package ratelimit
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Policy is how many requests a client can make. Requests can come in bursts
+// of up to Limit, and Limit more are allowed every Period. A Limit of 0 turns
+// the policy off.
+type Policy struct {
+	// Name keeps the buckets of the policy apart from those of the others.
+	Name   string
+	Limit  int
+	Period time.Duration
+}
+
+// The policies of the API. Global applies to every request, and the others
+// to the routes that need a tighter limit on top of it.
+var (
+	Global = &Policy{Name: "global", Limit: 300, Period: time.Minute}
+	// Auth applies to the routes that check credentials or send emails.
+	Auth = &Policy{Name: "auth", Limit: 10, Period: time.Minute}
+	// Write applies to the routes that create content or notify other users.
+	Write = &Policy{Name: "write", Limit: 60, Period: time.Minute}
+)
+
+// Bucket is the state of the bucket of a client, as kept by a Store.
+type Bucket struct {
+	Tokens  float64
+	Updated time.Time
+}
+
+// Result is what a request got from its bucket.
+type Result struct {
+	Allowed   bool
+	Remaining int
+	// RetryAfter is how long until the next token, when the request was not
+	// allowed.
+	RetryAfter time.Duration
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+}
+
+// Take refills bucket for the time since it was last updated and takes a
+// token from it when there is one. A bucket that was never updated is full.
+func (p Policy) Take(bucket *Bucket, now time.Time) Result {
+	limit := float64(p.Limit)
+	rate := limit / p.Period.Seconds()
+
+	if bucket.Updated.IsZero() {
+		bucket.Tokens = limit
+	} else if elapsed := now.Sub(bucket.Updated).Seconds(); elapsed > 0 {
+		bucket.Tokens = math.Min(limit, bucket.Tokens+elapsed*rate)
+	}
+	bucket.Updated = now
+
+	result := Result{Allowed: bucket.Tokens >= 1}
+	if result.Allowed {
+		bucket.Tokens--
+	} else {
+		result.RetryAfter = seconds((1 - bucket.Tokens) / rate)
+	}
+	result.Remaining = int(bucket.Tokens)
+	result.Reset = seconds((limit - bucket.Tokens) / rate)
+
+	return result
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// Store keeps the buckets of the clients.
+type Store interface {
+	// Take takes a token from the bucket of key, which follows policy.
+	Take(key string, policy Policy) (Result, error)
+}
+
+// StoreMemory keeps the buckets in memory. It is the only store for now.
+const StoreMemory = "memory"
+
+// Default is the store of every limit of the API.
+var Default Store = NewMemoryStore()
+
+// Init sets up Default with the store named by RATE_LIMIT_STORE, and reads
+// the policies from RATE_LIMIT_GLOBAL, RATE_LIMIT_AUTH and RATE_LIMIT_WRITE,
+// which look like 300/1m. Policies that are not set keep their default.
+func Init() error {
+	switch store := os.Getenv("RATE_LIMIT_STORE"); store {
+	case "", StoreMemory:
+		Default = NewMemoryStore()
+	default:
+		return fmt.Errorf("unknown rate limit store: %q", store)
+	}
+
+	for _, policy := range []*Policy{Global, Auth, Write} {
+		name := "RATE_LIMIT_" + strings.ToUpper(policy.Name)
+		if raw := os.Getenv(name); raw != "" {
+			limit, period, err := parsePolicy(raw)
+			if err != nil {
+				return fmt.Errorf("invalid %s: %q", name, raw)
+			}
+			policy.Limit, policy.Period = limit, period
+		}
+	}
+
+	return nil
+}
+
+// parsePolicy parses a limit and a period separated by a slash, like 300/1m.
+// 0 alone turns the limit off.
+func parsePolicy(raw string) (int, time.Duration, error) {
+	if raw == "0" {
+		return 0, time.Minute, nil
+	}
+
+	rawLimit, rawPeriod, ok := strings.Cut(raw, "/")
+	if !ok {
+		return 0, 0, errors.New("missing period")
+	}
+
+	limit, err := strconv.Atoi(rawLimit)
+	if err != nil || limit < 0 {
+		return 0, 0, errors.New("invalid limit")
+	}
+
+	period, err := time.ParseDuration(rawPeriod)
+	if err != nil || period <= 0 {
+		return 0, 0, errors.New("invalid period")
+	}
+
+	return limit, period, nil
+}
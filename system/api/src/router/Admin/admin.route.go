@@ -0,0 +1,23 @@
+package router
+
+import (
+	"social_api/controllers"
+	"social_api/middlewares"
+	"social_api/models"
+	"social_api/ratelimit"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupAdminRoutes(app *fiber.App) {
+	adminRouter := app.Group("/api")
+	authLimit := middlewares.RateLimit(ratelimit.Auth)
+	writeLimit := middlewares.RateLimit(ratelimit.Write)
+	moderator := middlewares.RequireRole(models.RoleModerator)
+	admin := middlewares.RequireRole(models.RoleAdmin)
+
+	adminRouter.Get("/admin/users", middlewares.RequireAuth, moderator, controllers.ListUsersHandler)
+	adminRouter.Post("/admin/users/:id/suspend", writeLimit, middlewares.RequireAuth, moderator, controllers.SuspendUserHandler)
+	adminRouter.Post("/admin/users/:id/unsuspend", writeLimit, middlewares.RequireAuth, moderator, controllers.UnsuspendUserHandler)
+	adminRouter.Post("/admin/users/:id/force-password-reset", authLimit, middlewares.RequireAuth, admin, controllers.ForcePasswordResetHandler)
+}
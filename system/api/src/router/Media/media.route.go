@@ -0,0 +1,22 @@
+package router
+
+import (
+	"social_api/controllers"
+	"social_api/middlewares"
+	"social_api/ratelimit"
+	"social_api/storage"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetUpMediaRoutes(app *fiber.App) {
+	// Files kept on the local filesystem are served by the API itself.
+	if local, ok := storage.Backend.(*storage.LocalStorage); ok {
+		app.Static(local.PathPrefix(), local.Dir)
+	}
+
+	mediaRouter := app.Group("/api")
+	writeLimit := middlewares.RateLimit(ratelimit.Write)
+
+	mediaRouter.Post("/media", writeLimit, middlewares.RequireAuth, controllers.UploadMediaHandler)
+}
@@ -1,13 +1,34 @@
 package router
 
-import "github.com/gofiber/fiber/v2"
+import (
+	"social_api/controllers"
+	"social_api/middlewares"
+	"social_api/ratelimit"
+
+	"github.com/gofiber/fiber/v2"
+)
 
 func SetUpPostsRoutes(app *fiber.App) {
 	postsRouter := app.Group("/api")
+	writeLimit := middlewares.RateLimit(ratelimit.Write)
+
+	postsRouter.Get("/feed", middlewares.RequireAuth, controllers.FeedHandler)
+
+	postsRouter.Get("/posts", controllers.GetPostsHandler)
+	postsRouter.Get("/posts/:id", controllers.GetPostHandler)
+	postsRouter.Post("/posts", writeLimit, middlewares.RequireAuth, middlewares.RequireVerifiedEmail, middlewares.ValidateCreatePostSchema, controllers.CreatePostHandler)
+	postsRouter.Patch("/posts/:id", writeLimit, middlewares.RequireAuth, middlewares.ValidateUpdatePostSchema, controllers.UpdatePostHandler)
+	postsRouter.Delete("/posts/:id", writeLimit, middlewares.RequireAuth, controllers.DeletePostHandler)
 
-	postsRouter.Get("/posts", func(c *fiber.Ctx) error {
-		return nil
-	})
+	postsRouter.Get("/posts/:id/responses", controllers.GetResponsesHandler)
+	postsRouter.Post("/posts/:id/responses", writeLimit, middlewares.RequireAuth, middlewares.RequireVerifiedEmail, middlewares.ValidateCreateResponseSchema, controllers.CreateResponseHandler)
+	postsRouter.Patch("/responses/:id", writeLimit, middlewares.RequireAuth, middlewares.ValidateUpdateResponseSchema, controllers.UpdateResponseHandler)
+	postsRouter.Delete("/responses/:id", writeLimit, middlewares.RequireAuth, controllers.DeleteResponseHandler)
 
-	// On development
+	postsRouter.Get("/posts/:id/likes", controllers.GetPostLikesHandler)
+	postsRouter.Post("/posts/:id/like", writeLimit, middlewares.RequireAuth, controllers.LikePostHandler)
+	postsRouter.Post("/posts/:id/unlike", writeLimit, middlewares.RequireAuth, controllers.UnlikePostHandler)
+	postsRouter.Get("/responses/:id/likes", controllers.GetResponseLikesHandler)
+	postsRouter.Post("/responses/:id/like", writeLimit, middlewares.RequireAuth, controllers.LikeResponseHandler)
+	postsRouter.Post("/responses/:id/unlike", writeLimit, middlewares.RequireAuth, controllers.UnlikeResponseHandler)
 }
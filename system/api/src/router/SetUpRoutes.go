@@ -1,16 +1,35 @@
 package router
 
 import (
+	"social_api/controllers"
+	"social_api/middlewares"
+	"social_api/ratelimit"
+	admin "social_api/router/Admin"
+	media "social_api/router/Media"
 	posts "social_api/router/Posts"
 	social "social_api/router/Social"
 	users "social_api/router/Users"
+	wellknown "social_api/router/WellKnown"
 
 	"github.com/gofiber/fiber/v2"
 )
 
 func SetupRoutes(app *fiber.App) {
+	app.Use(middlewares.RateLimit(ratelimit.Global))
+	app.Use(middlewares.LimitBody(map[string]int{
+		"/api/media":          controllers.MaxUploadBodySize,
+		"/api/update-picture": controllers.MaxPictureBodySize,
+	}))
+
 	users.SetupAuthenticationRoutes(app)
 	posts.SetUpPostsRoutes(app)
+	media.SetUpMediaRoutes(app)
 	social.SetUpSocialRoutes(app)
 	users.SetupUserSettiingsRoutes(app)
+	users.SetupSessionsRoutes(app)
+	users.SetupTwoFactorRoutes(app)
+	users.SetupPasskeysRoutes(app)
+	users.SetupOIDCRoutes(app)
+	wellknown.SetupWellKnownRoutes(app)
+	admin.SetupAdminRoutes(app)
 }
@@ -3,15 +3,18 @@ package router
 import (
 	"social_api/controllers"
 	"social_api/middlewares"
+	"social_api/ratelimit"
 
 	"github.com/gofiber/fiber/v2"
 )
 
 func SetUpSocialRoutes(app *fiber.App) {
 	socialsRouter := app.Group("/api")
+	writeLimit := middlewares.RateLimit(ratelimit.Write)
 
-	socialsRouter.Get("/profile/:id", middlewares.RenewJWTMiddleware, controllers.ProfileHandler)
-	socialsRouter.Post("/followuser/:id", middlewares.RenewJWTMiddleware, controllers.FollowUserHandler)
-	socialsRouter.Post("/unfollowuser/:id", middlewares.RenewJWTMiddleware, controllers.UnFollowUserHandler)
-	socialsRouter.Get("/getfollowers/:id", middlewares.RenewJWTMiddleware, controllers.GetFollowersHandler)
+	socialsRouter.Get("/profile/:id", middlewares.OptionalAuth, controllers.ProfileHandler)
+	socialsRouter.Post("/followuser/:id", writeLimit, middlewares.RequireAuth, controllers.FollowUserHandler)
+	socialsRouter.Post("/unfollowuser/:id", writeLimit, middlewares.RequireAuth, controllers.UnFollowUserHandler)
+	socialsRouter.Get("/getfollowers/:id", controllers.GetFollowersHandler)
+	socialsRouter.Get("/users/:id/following", controllers.GetFollowingHandler)
 }
@@ -3,15 +3,24 @@ package router
 import (
 	"social_api/controllers"
 	"social_api/middlewares"
+	"social_api/ratelimit"
 
 	"github.com/gofiber/fiber/v2"
 )
 
 func SetupAuthenticationRoutes(app *fiber.App) {
 	authRouter := app.Group("/api")
+	authLimit := middlewares.RateLimit(ratelimit.Auth)
+	loginLimit := middlewares.RateLimitByIP(ratelimit.Auth)
 
-	authRouter.Post("/register", middlewares.ValidateRegisterSchema, controllers.RegisterHandler)
-	authRouter.Post("/login", middlewares.ValidateLoginSchema, controllers.LoginHandler)
-	authRouter.Post("/logout", controllers.LogoutHandler)
-	authRouter.Get("/profile", middlewares.RenewJWTMiddleware, controllers.PrivateProfileHandler)
+	authRouter.Post("/register", loginLimit, middlewares.ValidateRegisterSchema, controllers.RegisterHandler)
+	authRouter.Post("/login", loginLimit, middlewares.ValidateLoginSchema, controllers.LoginHandler)
+	authRouter.Get("/verify-email", controllers.VerifyEmailHandler)
+	authRouter.Post("/verify-email/resend", authLimit, middlewares.RequireAuth, controllers.ResendVerificationHandler)
+	authRouter.Post("/password/forgot", loginLimit, middlewares.ValidateForgotPasswordSchema, controllers.ForgotPasswordHandler)
+	authRouter.Post("/password/reset", loginLimit, middlewares.ValidateResetPasswordSchema, controllers.ResetPasswordHandler)
+	authRouter.Post("/token/refresh", loginLimit, middlewares.ValidateRefreshTokenSchema, controllers.RefreshTokenHandler)
+	authRouter.Post("/logout", middlewares.RequireAuth, controllers.LogoutHandler)
+	authRouter.Post("/logout-all", middlewares.RequireAuth, controllers.LogoutAllHandler)
+	authRouter.Get("/profile", middlewares.RequireAuth, controllers.PrivateProfileHandler)
 }
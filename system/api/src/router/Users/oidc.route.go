@@ -0,0 +1,17 @@
+package router
+
+import (
+	"social_api/controllers"
+	"social_api/middlewares"
+	"social_api/ratelimit"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupOIDCRoutes(app *fiber.App) {
+	oidcRouter := app.Group("/api")
+	loginLimit := middlewares.RateLimitByIP(ratelimit.Auth)
+
+	oidcRouter.Get("/login/oidc/:provider", loginLimit, controllers.OIDCLoginHandler)
+	oidcRouter.Get("/login/oidc/:provider/callback", loginLimit, controllers.OIDCCallbackHandler)
+}
@@ -0,0 +1,21 @@
+package router
+
+import (
+	"social_api/controllers"
+	"social_api/middlewares"
+	"social_api/ratelimit"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupPasskeysRoutes(app *fiber.App) {
+	passkeysRouter := app.Group("/api")
+	loginLimit := middlewares.RateLimitByIP(ratelimit.Auth)
+
+	passkeysRouter.Post("/login/passkey/begin", loginLimit, controllers.BeginPasskeyLoginHandler)
+	passkeysRouter.Post("/login/passkey/finish", loginLimit, middlewares.ValidatePasskeyLoginSchema, controllers.FinishPasskeyLoginHandler)
+	passkeysRouter.Get("/passkeys", middlewares.RequireAuth, controllers.GetPasskeysHandler)
+	passkeysRouter.Post("/passkeys/register/begin", middlewares.RequireAuth, controllers.BeginPasskeyRegistrationHandler)
+	passkeysRouter.Post("/passkeys/register/finish", middlewares.RequireAuth, middlewares.ValidatePasskeyRegistrationSchema, controllers.FinishPasskeyRegistrationHandler)
+	passkeysRouter.Delete("/passkeys/:id", middlewares.RequireAuth, controllers.DeletePasskeyHandler)
+}
@@ -0,0 +1,15 @@
+package router
+
+import (
+	"social_api/controllers"
+	"social_api/middlewares"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupSessionsRoutes(app *fiber.App) {
+	sessionsRouter := app.Group("/api")
+
+	sessionsRouter.Get("/sessions", middlewares.RequireAuth, controllers.GetSessionsHandler)
+	sessionsRouter.Delete("/sessions/:id", middlewares.RequireAuth, controllers.RevokeSessionHandler)
+}
@@ -0,0 +1,20 @@
+package router
+
+import (
+	"social_api/controllers"
+	"social_api/middlewares"
+	"social_api/ratelimit"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupTwoFactorRoutes(app *fiber.App) {
+	twoFactorRouter := app.Group("/api")
+	authLimit := middlewares.RateLimit(ratelimit.Auth)
+	loginLimit := middlewares.RateLimitByIP(ratelimit.Auth)
+
+	twoFactorRouter.Post("/login/2fa", loginLimit, middlewares.ValidateLoginTwoFactorSchema, controllers.LoginTwoFactorHandler)
+	twoFactorRouter.Post("/2fa/setup", middlewares.RequireAuth, controllers.SetupTwoFactorHandler)
+	twoFactorRouter.Post("/2fa/confirm", middlewares.RequireAuth, middlewares.ValidateConfirmTwoFactorSchema, controllers.ConfirmTwoFactorHandler)
+	twoFactorRouter.Post("/2fa/disable", authLimit, middlewares.RequireAuth, middlewares.ValidateDisableTwoFactorSchema, controllers.DisableTwoFactorHandler)
+}
@@ -3,13 +3,18 @@ package router
 import (
 	"social_api/controllers"
 	"social_api/middlewares"
+	"social_api/ratelimit"
 
 	"github.com/gofiber/fiber/v2"
 )
 
 func SetupUserSettiingsRoutes(app *fiber.App) {
 	userSettingsRouter := app.Group("/api")
+	authLimit := middlewares.RateLimit(ratelimit.Auth)
+	writeLimit := middlewares.RateLimit(ratelimit.Write)
 
-	userSettingsRouter.Post("/update-username", middlewares.RenewJWTMiddleware, controllers.UpdateUsernameHandler)
-	userSettingsRouter.Post("/update-password", middlewares.RenewJWTMiddleware, controllers.UpdatePasswordHandler)
+	userSettingsRouter.Post("/update-username", writeLimit, middlewares.RequireAuth, controllers.UpdateUsernameHandler)
+	userSettingsRouter.Post("/update-password", authLimit, middlewares.RequireAuth, middlewares.ValidateUpdatePasswordSchema, controllers.UpdatePasswordHandler)
+	userSettingsRouter.Post("/update-picture", writeLimit, middlewares.RequireAuth, controllers.UpdatePictureHandler)
+	userSettingsRouter.Post("/update-description", writeLimit, middlewares.RequireAuth, middlewares.ValidateUpdateDescriptionSchema, controllers.UpdateDescriptionHandler)
 }
@@ -0,0 +1,13 @@
+package router
+
+import (
+	"social_api/controllers"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupWellKnownRoutes(app *fiber.App) {
+	wellKnownRouter := app.Group("/.well-known")
+
+	wellKnownRouter.Get("/jwks.json", controllers.JWKSHandler)
+}
@@ -1,13 +1,24 @@
 package schemas
 
 import (
+	"encoding/json"
 	"errors"
 
+	"social_api/passwords"
+
 	"github.com/go-playground/validator/v10"
 )
 
 var validate = validator.New()
 
+func init() {
+	// The password tag checks a field against passwords.Default, so every
+	// request that sets a password follows the same policy.
+	validate.RegisterValidation("password", func(fl validator.FieldLevel) bool {
+		return passwords.Default.Check(fl.Field().String()) == nil
+	})
+}
+
 var (
 	ErrValidation = errors.New("Validation error")
 )
@@ -24,7 +35,7 @@ func (e *ValidationError) Error() string {
 type RegistrationRequest struct {
 	Username  string `json:"username" validate:"required,min=3,max=20"`
 	Email     string `json:"email" validate:"required,email,max=35"`
-	Password  string `json:"password" validate:"required,min=6,max=45"`
+	Password  string `json:"password" validate:"required,password"`
 	Firstname string `json:"firstname" validate:"required,max=18"`
 	Lastname  string `json:"lastname" validate:"required,max=50"`
 }
@@ -35,12 +46,57 @@ type LoginRequest struct {
 	Password string `json:"password" validate:"required"`
 }
 
+type LoginTwoFactorRequest struct {
+	MFAToken string `json:"mfa_token" validate:"required"`
+	Code     string `json:"code" validate:"required"`
+}
+
+type ConfirmTwoFactorRequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+type DisableTwoFactorRequest struct {
+	Password string `json:"password" validate:"required"`
+	Code     string `json:"code" validate:"required"`
+}
+
+// PasskeyRegistrationRequest carries the PublicKeyCredential the browser
+// returned from navigator.credentials.create, as JSON.
+type PasskeyRegistrationRequest struct {
+	Name       string          `json:"name" validate:"max=64"`
+	Credential json.RawMessage `json:"credential" validate:"required"`
+}
+
+// PasskeyLoginRequest carries the PublicKeyCredential the browser returned
+// from navigator.credentials.get, as JSON.
+type PasskeyLoginRequest struct {
+	Credential json.RawMessage `json:"credential" validate:"required"`
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,password"`
+}
+
 type UpdateUsernameRequest struct {
 	Username string `json:"username" validate:"required,min=3,max=50"`
 }
 
 type UpdatePasswordRequest struct {
-	Password string `json:"password" validate:"required,min=6"`
+	CurrentPassword string `json:"currentPassword" validate:"required"`
+	Password        string `json:"password" validate:"required,password"`
+}
+
+type UpdateDescriptionRequest struct {
+	Description string `json:"description" validate:"max=255"`
 }
 
 func Validate(request interface{}) error {
@@ -0,0 +1,31 @@
+package schemas
+
+import "social_api/models"
+
+type CreatePostRequest struct {
+	Title       string         `json:"title" validate:"required,max=100"`
+	Description string         `json:"description" validate:"required,max=2000"`
+	Images      []models.Image `json:"images" validate:"max=10,dive"`
+	Videos      []string       `json:"videos" validate:"max=4,dive,url"`
+}
+
+// UpdatePostRequest only touches the fields that are present in the body.
+type UpdatePostRequest struct {
+	Title       *string         `json:"title" validate:"omitempty,min=1,max=100"`
+	Description *string         `json:"description" validate:"omitempty,min=1,max=2000"`
+	Images      *[]models.Image `json:"images" validate:"omitempty,max=10,dive"`
+	Videos      *[]string       `json:"videos" validate:"omitempty,max=4,dive,url"`
+}
+
+type CreateResponseRequest struct {
+	ParentID string         `json:"parentId" validate:"omitempty,uuid"`
+	Content  string         `json:"content" validate:"required,max=2000"`
+	Images   []models.Image `json:"images" validate:"max=4,dive"`
+	Videos   []string       `json:"videos" validate:"max=1,dive,url"`
+}
+
+type UpdateResponseRequest struct {
+	Content *string         `json:"content" validate:"omitempty,min=1,max=2000"`
+	Images  *[]models.Image `json:"images" validate:"omitempty,max=4,dive"`
+	Videos  *[]string       `json:"videos" validate:"omitempty,max=1,dive,url"`
+}
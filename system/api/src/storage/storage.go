@@ -0,0 +1,52 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Storage stores uploaded files and tells the public URL they are served from.
+type Storage interface {
+	// Save stores content under key, replacing any previous file with the
+	// same key, and returns its URL.
+	Save(key string, content io.Reader, contentType string) (string, error)
+	Delete(key string) error
+	URL(key string) string
+}
+
+var Backend Storage
+
+var ErrUnknownBackend = errors.New("Unknown storage backend")
+
+// Init sets Backend to the implementation named by the STORAGE_BACKEND
+// environment variable.
+func Init() error {
+	switch backend := os.Getenv("STORAGE_BACKEND"); backend {
+	case "", "local":
+		dir := os.Getenv("MEDIA_DIR")
+		if dir == "" {
+			dir = "./media"
+		}
+
+		baseURL := os.Getenv("MEDIA_BASE_URL")
+		if baseURL == "" {
+			port := os.Getenv("PORT")
+			if port == "" {
+				port = "3000"
+			}
+			baseURL = "http://localhost:" + port + "/media"
+		}
+
+		local, err := NewLocalStorage(dir, baseURL)
+		if err != nil {
+			return err
+		}
+
+		Backend = local
+		return nil
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownBackend, backend)
+	}
+}
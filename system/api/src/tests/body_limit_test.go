@@ -0,0 +1,83 @@
+package tests
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"social_api/middlewares"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimitBody(t *testing.T) {
+	app := fiber.New(fiber.Config{
+		BodyLimit:                    middlewares.DefaultBodyLimit,
+		StreamRequestBody:            true,
+		DisablePreParseMultipartForm: true,
+	})
+	uploadLimit := middlewares.DefaultBodyLimit + 2<<20
+	app.Use(middlewares.LimitBody(map[string]int{"/upload": uploadLimit}))
+
+	app.Post("/json", func(c *fiber.Ctx) error {
+		return c.SendString(strconv.Itoa(len(c.Body())))
+	})
+	app.Post("/upload", func(c *fiber.Ctx) error {
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			return err
+		}
+		return c.SendString(strconv.FormatInt(fileHeader.Size, 10))
+	})
+
+	send := func(req *http.Request) (int, string) {
+		resp, err := app.Test(req, -1)
+		require.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(body)
+	}
+
+	upload := func(size int) *http.Request {
+		var body bytes.Buffer
+		writer := multipart.NewWriter(&body)
+		part, err := writer.CreateFormFile("file", "video.mp4")
+		require.NoError(t, err)
+		_, err = part.Write(bytes.Repeat([]byte("a"), size))
+		require.NoError(t, err)
+		require.NoError(t, writer.Close())
+
+		req := httptest.NewRequest("POST", "/upload", &body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		return req
+	}
+
+	status, body := send(httptest.NewRequest("POST", "/json", strings.NewReader(`{"title": "Hello"}`)))
+	assert.Equal(t, 200, status)
+	assert.Equal(t, "18", body)
+
+	status, _ = send(httptest.NewRequest("POST", "/json", bytes.NewReader(make([]byte, middlewares.DefaultBodyLimit+1))))
+	assert.Equal(t, fiber.StatusRequestEntityTooLarge, status, "other routes keep the default limit")
+
+	// Without a known length, the size of the body cannot be checked.
+	chunked := httptest.NewRequest("POST", "/json", strings.NewReader(`{}`))
+	chunked.ContentLength = -1
+	chunked.TransferEncoding = []string{"chunked"}
+	status, _ = send(chunked)
+	assert.Equal(t, fiber.StatusLengthRequired, status)
+
+	size := middlewares.DefaultBodyLimit + 1<<20
+	status, body = send(upload(size))
+	assert.Equal(t, 200, status, "the upload route takes bigger bodies")
+	assert.Equal(t, strconv.Itoa(size), body)
+
+	status, _ = send(upload(uploadLimit))
+	assert.Equal(t, fiber.StatusRequestEntityTooLarge, status, "up to its own limit")
+}
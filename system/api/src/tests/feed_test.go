@@ -0,0 +1,251 @@
+package tests
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"social_api/feed"
+	"social_api/models"
+	"social_api/utils"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryStore is a feed.Store that keeps the followers graph and the posts in memory.
+type memoryStore struct {
+	following map[string]map[string]bool
+	posts     map[string]models.Post
+	deleted   map[string]bool
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{
+		following: make(map[string]map[string]bool),
+		posts:     make(map[string]models.Post),
+		deleted:   make(map[string]bool),
+	}
+}
+
+func (s *memoryStore) follow(followerID, followedID string) {
+	if s.following[followerID] == nil {
+		s.following[followerID] = make(map[string]bool)
+	}
+	s.following[followerID][followedID] = true
+}
+
+func (s *memoryStore) Followers(authorID string) ([]string, error) {
+	followers := make([]string, 0)
+	for followerID, followed := range s.following {
+		if followed[authorID] {
+			followers = append(followers, followerID)
+		}
+	}
+	return followers, nil
+}
+
+func (s *memoryStore) FollowingPosts(userID string, cursor *utils.Cursor, limit int) ([]models.Post, error) {
+	posts := make([]models.Post, 0)
+	for _, post := range s.posts {
+		if s.deleted[post.ID] || !s.following[userID][post.AuthorID] {
+			continue
+		}
+		if cursor != nil && !postBefore(models.Post{ID: cursor.ID, CreatedAt: cursor.Time}, post) {
+			continue
+		}
+		posts = append(posts, post)
+	}
+
+	sort.Slice(posts, func(i, j int) bool { return postBefore(posts[i], posts[j]) })
+	if len(posts) > limit {
+		posts = posts[:limit]
+	}
+	return posts, nil
+}
+
+func (s *memoryStore) Posts(postIDs []string) ([]models.Post, error) {
+	posts := make([]models.Post, 0, len(postIDs))
+	for _, postID := range postIDs {
+		if post, ok := s.posts[postID]; ok && !s.deleted[postID] {
+			posts = append(posts, post)
+		}
+	}
+	return posts, nil
+}
+
+// postBefore orders posts like the database feed: newest first, then by descending ID.
+func postBefore(a, b models.Post) bool {
+	if !a.CreatedAt.Equal(b.CreatedAt) {
+		return a.CreatedAt.After(b.CreatedAt)
+	}
+	return a.ID > b.ID
+}
+
+// readTimeline walks every page of the timeline and returns the post IDs.
+func readTimeline(t *testing.T, builder feed.Builder, userID string, pageSize int) []string {
+	postIDs := make([]string, 0)
+	var cursor *utils.Cursor
+
+	for {
+		posts, err := builder.Timeline(userID, cursor, pageSize)
+		if err != nil {
+			t.Fatalf("Error reading timeline: %v", err)
+		}
+
+		for _, post := range posts {
+			postIDs = append(postIDs, post.ID)
+		}
+
+		if len(posts) < pageSize {
+			return postIDs
+		}
+
+		last := posts[len(posts)-1]
+		cursor = &utils.Cursor{Time: last.CreatedAt, ID: last.ID}
+	}
+}
+
+func TestFeedStrategiesMatch(t *testing.T) {
+	store := newMemoryStore()
+	pull := feed.NewPullBuilder(store)
+	push := feed.NewPushBuilder(store, 10, 100)
+	builders := []feed.Builder{pull, push}
+
+	reader := "reader"
+	authors := []string{"alice", "bob", "carol", "dave"}
+	store.follow(reader, "alice")
+	store.follow(reader, "bob")
+	store.follow(reader, "carol")
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	postCount := 0
+	createPost := func(authorID string) models.Post {
+		postCount++
+		post := models.Post{
+			ID:       fmt.Sprintf("post-%03d", postCount),
+			AuthorID: authorID,
+			// Every third post shares its creation time with the previous one,
+			// so the ID has to break the tie.
+			CreatedAt: start.Add(time.Duration(postCount-postCount/3) * time.Minute),
+		}
+		store.posts[post.ID] = post
+		for _, builder := range builders {
+			assert.NoError(t, builder.PostCreated(post))
+		}
+		return post
+	}
+
+	assertSameTimeline := func(step string) {
+		for _, pageSize := range []int{1, 4, 7, 50} {
+			expected := readTimeline(t, pull, reader, pageSize)
+			actual := readTimeline(t, push, reader, pageSize)
+			assert.Equal(t, expected, actual, "%s: timelines differ with pages of %d", step, pageSize)
+		}
+	}
+
+	for i := 0; i < 24; i++ {
+		createPost(authors[i%len(authors)])
+	}
+	assertSameTimeline("initial posts")
+
+	var deleted []models.Post
+	for i := 0; i < 12; i++ {
+		post := createPost(authors[i%len(authors)])
+		if i%5 == 0 {
+			deleted = append(deleted, post)
+		}
+	}
+	assertSameTimeline("posts created after the timeline was built")
+
+	for _, post := range deleted {
+		store.deleted[post.ID] = true
+		for _, builder := range builders {
+			assert.NoError(t, builder.PostDeleted(post))
+		}
+	}
+	assertSameTimeline("deleted posts")
+
+	store.follow(reader, "dave")
+	for _, builder := range builders {
+		assert.NoError(t, builder.FollowChanged(reader))
+	}
+	assertSameTimeline("new followed user")
+}
+
+func TestUnknownFeedStrategy(t *testing.T) {
+	_, err := feed.New("sideways", newMemoryStore())
+	assert.ErrorIs(t, err, utils.ErrFeedStrategy)
+}
+
+// slowStore is a feed.Store whose FollowingPosts waits for proceed once it
+// read the posts, to change things while a timeline is being built.
+type slowStore struct {
+	*memoryStore
+	read    chan struct{}
+	proceed chan struct{}
+}
+
+func (s *slowStore) FollowingPosts(userID string, cursor *utils.Cursor, limit int) ([]models.Post, error) {
+	posts, err := s.memoryStore.FollowingPosts(userID, cursor, limit)
+	if s.read != nil {
+		close(s.read)
+		<-s.proceed
+		s.read = nil
+	}
+	return posts, err
+}
+
+func TestPushBuilderChangesDuringBuild(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for _, change := range []string{"post created", "follow changed"} {
+		inner := newMemoryStore()
+		inner.follow("reader", "alice")
+		inner.posts["old"] = models.Post{ID: "old", AuthorID: "alice", CreatedAt: start}
+		store := &slowStore{memoryStore: inner, read: make(chan struct{}), proceed: make(chan struct{})}
+		push := feed.NewPushBuilder(store, 10, 100)
+
+		done := make(chan []string)
+		go func() { done <- readTimeline(t, push, "reader", 10) }()
+
+		// The first read of the timeline has read the store, but has not
+		// kept the timeline yet.
+		<-store.read
+		switch change {
+		case "post created":
+			post := models.Post{ID: "new", AuthorID: "alice", CreatedAt: start.Add(time.Minute)}
+			inner.posts[post.ID] = post
+			assert.NoError(t, push.PostCreated(post))
+		case "follow changed":
+			inner.posts["bob"] = models.Post{ID: "bob", AuthorID: "bob", CreatedAt: start.Add(time.Minute)}
+			inner.follow("reader", "bob")
+			assert.NoError(t, push.FollowChanged("reader"))
+		}
+		close(store.proceed)
+
+		expected := readTimeline(t, feed.NewPullBuilder(inner), "reader", 10)
+		assert.Equal(t, expected, <-done, change)
+		assert.Equal(t, expected, readTimeline(t, push, "reader", 10), change)
+	}
+}
+
+func TestPushBuilderForgetsOldTimelines(t *testing.T) {
+	store := newMemoryStore()
+	push := feed.NewPushBuilder(store, 10, 2)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for _, reader := range []string{"first", "second", "third"} {
+		store.follow(reader, "alice")
+		readTimeline(t, push, reader, 10)
+	}
+
+	// The timeline of first was dropped, so the post is only in the store.
+	post := models.Post{ID: "post", AuthorID: "alice", CreatedAt: start}
+	store.posts[post.ID] = post
+	assert.NoError(t, push.PostCreated(post))
+
+	for _, reader := range []string{"first", "second", "third"} {
+		assert.Equal(t, []string{"post"}, readTimeline(t, push, reader, 10), reader)
+	}
+}
@@ -0,0 +1,103 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+
+	"social_api/imaging"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// jpegWithOrientation encodes a width x height JPEG with an EXIF segment that
+// only holds the orientation tag.
+func jpegWithOrientation(t *testing.T, width, height int, orientation uint16) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			img.Set(x, y, color.RGBA{R: uint8(x * 255 / width), G: 128, B: 64, A: 255})
+		}
+	}
+
+	var encoded bytes.Buffer
+	if err := jpeg.Encode(&encoded, img, nil); err != nil {
+		t.Fatalf("Error encoding JPEG: %v", err)
+	}
+
+	var tiff bytes.Buffer
+	tiff.WriteString("MM\x00\x2a")
+	binary.Write(&tiff, binary.BigEndian, uint32(8))
+	binary.Write(&tiff, binary.BigEndian, uint16(1))
+	binary.Write(&tiff, binary.BigEndian, []uint16{0x0112, 3})
+	binary.Write(&tiff, binary.BigEndian, uint32(1))
+	binary.Write(&tiff, binary.BigEndian, []uint16{orientation, 0})
+	binary.Write(&tiff, binary.BigEndian, uint32(0))
+
+	segment := append([]byte("Exif\x00\x00"), tiff.Bytes()...)
+
+	var withExif bytes.Buffer
+	withExif.Write(encoded.Bytes()[:2])
+	withExif.Write([]byte{0xFF, 0xE1})
+	binary.Write(&withExif, binary.BigEndian, uint16(len(segment)+2))
+	withExif.Write(segment)
+	withExif.Write(encoded.Bytes()[2:])
+
+	return withExif.Bytes()
+}
+
+func TestImageOrientationAndExifStripping(t *testing.T) {
+	data := jpegWithOrientation(t, 300, 200, 6)
+
+	img, err := imaging.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Error decoding image: %v", err)
+	}
+	assert.Equal(t, 200, img.Bounds().Dx(), "orientation 6 should turn the image sideways")
+	assert.Equal(t, 300, img.Bounds().Dy())
+
+	variants, err := imaging.Variants(img, imaging.VariantSizes)
+	if err != nil {
+		t.Fatalf("Error generating variants: %v", err)
+	}
+
+	// The image is smaller than 1024 pixels, so it is not scaled up.
+	sizes := make([]image.Point, 0, len(variants))
+	for _, variant := range variants {
+		sizes = append(sizes, image.Pt(variant.Width, variant.Height))
+		assert.Equal(t, "image/jpeg", variant.ContentType)
+		assert.False(t, bytes.Contains(variant.Data, []byte("Exif")), "variants must not carry EXIF data")
+	}
+	assert.Equal(t, []image.Point{{42, 64}, {170, 256}, {200, 300}}, sizes)
+}
+
+func TestTransparentImagesStayPNG(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 10, 10))
+
+	variants, err := imaging.Variants(img, imaging.VariantSizes)
+	if err != nil {
+		t.Fatalf("Error generating variants: %v", err)
+	}
+
+	assert.Len(t, variants, 1)
+	assert.Equal(t, "image/png", variants[0].ContentType)
+}
+
+func TestBlurhash(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 64, 48))
+	for x := 0; x < 64; x++ {
+		for y := 0; y < 48; y++ {
+			img.Set(x, y, color.RGBA{R: uint8(x * 4), G: uint8(y * 5), B: 200, A: 255})
+		}
+	}
+
+	hash := imaging.Blurhash(img, 4, 3)
+
+	// One size flag, one maximum, four DC and two for each of the 11 AC components.
+	assert.Len(t, hash, 28)
+	assert.Equal(t, hash, imaging.Blurhash(img, 4, 3))
+	assert.Equal(t, "L", hash[:1], "the size flag encodes 4x3 components")
+}
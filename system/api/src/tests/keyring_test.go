@@ -0,0 +1,234 @@
+package tests
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"social_api/models"
+	router "social_api/router/WellKnown"
+	"social_api/tokens"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRSAKey(t *testing.T) *tokens.Key {
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	key, err := tokens.NewKey(private, nil)
+	require.NoError(t, err)
+	return key
+}
+
+func newEd25519Key(t *testing.T) *tokens.Key {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	key, err := tokens.NewKey(private, nil)
+	require.NoError(t, err)
+	return key
+}
+
+// publicOnly returns key without its private key, like the keys of
+// JWT_VERIFICATION_KEY_FILES usually are.
+func publicOnly(t *testing.T, key *tokens.Key) *tokens.Key {
+	public, err := tokens.NewKey(nil, key.Public)
+	require.NoError(t, err)
+	return public
+}
+
+func testClaims() tokens.AccessClaims {
+	return tokens.AccessClaims{
+		UserID:    testUserID,
+		SessionID: testSessionID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		},
+	}
+}
+
+func TestKeyringSignsWithKid(t *testing.T) {
+	for name, key := range map[string]*tokens.Key{"RS256": newRSAKey(t), "EdDSA": newEd25519Key(t)} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, name, key.Algorithm)
+
+			keyring, err := tokens.NewKeyring(key)
+			require.NoError(t, err)
+
+			token, err := keyring.Sign(testClaims())
+			require.NoError(t, err)
+
+			parsed, _, err := jwt.NewParser().ParseUnverified(token, &tokens.AccessClaims{})
+			require.NoError(t, err)
+			assert.Equal(t, name, parsed.Header["alg"])
+			assert.Equal(t, key.ID, parsed.Header["kid"])
+
+			claims := &tokens.AccessClaims{}
+			require.NoError(t, keyring.Parse(token, claims))
+			assert.Equal(t, testSessionID, claims.SessionID)
+		})
+	}
+}
+
+func TestKeyringRotation(t *testing.T) {
+	previous := newRSAKey(t)
+	current := newEd25519Key(t)
+
+	before, err := tokens.NewKeyring(previous)
+	require.NoError(t, err)
+	oldToken, err := before.Sign(testClaims())
+	require.NoError(t, err)
+
+	// Right after the rotation, tokens of the previous key still work.
+	during, err := tokens.NewKeyring(current, publicOnly(t, previous))
+	require.NoError(t, err)
+	assert.NoError(t, during.Parse(oldToken, &tokens.AccessClaims{}))
+
+	newToken, err := during.Sign(testClaims())
+	require.NoError(t, err)
+	assert.NoError(t, during.Parse(newToken, &tokens.AccessClaims{}))
+
+	// Once the previous key is dropped, its tokens stop working.
+	after, err := tokens.NewKeyring(current)
+	require.NoError(t, err)
+	assert.Error(t, after.Parse(oldToken, &tokens.AccessClaims{}))
+	assert.NoError(t, after.Parse(newToken, &tokens.AccessClaims{}))
+
+	_, err = tokens.NewKeyring(publicOnly(t, current))
+	assert.Error(t, err, "the signing key needs its private key")
+}
+
+func TestKeyringRefusesForgedTokens(t *testing.T) {
+	key := newRSAKey(t)
+	keyring, err := tokens.NewKeyring(key)
+	require.NoError(t, err)
+
+	der, err := x509.MarshalPKIXPublicKey(key.Public)
+	require.NoError(t, err)
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+
+	// HS256 with the public key as secret, which a verifier that trusts the
+	// alg header would check with that same public key.
+	confused := jwt.NewWithClaims(jwt.SigningMethodHS256, testClaims())
+	confused.Header["kid"] = key.ID
+	confusedToken, err := confused.SignedString(publicPEM)
+	require.NoError(t, err)
+
+	unsigned := jwt.NewWithClaims(jwt.SigningMethodNone, testClaims())
+	unsigned.Header["kid"] = key.ID
+	unsignedToken, err := unsigned.SignedString(jwt.UnsafeAllowNoneSignatureType)
+	require.NoError(t, err)
+
+	other, err := tokens.NewKeyring(newRSAKey(t))
+	require.NoError(t, err)
+	otherToken, err := other.Sign(testClaims())
+	require.NoError(t, err)
+
+	withoutKid := jwt.NewWithClaims(jwt.SigningMethodRS256, testClaims())
+	withoutKidToken, err := withoutKid.SignedString(key.Private)
+	require.NoError(t, err)
+
+	for name, token := range map[string]string{
+		"alg confusion": confusedToken,
+		"alg none":      unsignedToken,
+		"unknown kid":   otherToken,
+		"without kid":   withoutKidToken,
+	} {
+		assert.Error(t, keyring.Parse(token, &tokens.AccessClaims{}), name)
+	}
+}
+
+func TestParseKeyPEM(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(edKey)
+	require.NoError(t, err)
+	pkix, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	require.NoError(t, err)
+
+	blocks := map[string]*pem.Block{
+		"PKCS #8":  {Type: "PRIVATE KEY", Bytes: pkcs8},
+		"PKCS #1":  {Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)},
+		"PKIX":     {Type: "PUBLIC KEY", Bytes: pkix},
+		"RSA 1024": {Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(smallRSAKey(t))},
+	}
+
+	for name, block := range blocks {
+		key, err := tokens.ParseKeyPEM(pem.EncodeToMemory(block))
+		if name == "RSA 1024" {
+			assert.Error(t, err, "RSA keys need at least 2048 bits")
+			continue
+		}
+		require.NoError(t, err, name)
+		assert.Equal(t, name != "PKIX", key.Private != nil, name)
+	}
+
+	_, err = tokens.ParseKeyPEM([]byte("not a key"))
+	assert.Error(t, err)
+}
+
+func smallRSAKey(t *testing.T) *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+	return key
+}
+
+func TestJWKSEndpoint(t *testing.T) {
+	previous := newRSAKey(t)
+	current := newEd25519Key(t)
+	keyring, err := tokens.NewKeyring(current, publicOnly(t, previous))
+	require.NoError(t, err)
+	useTestTokens(t).Keyring = keyring
+
+	app := fiber.New()
+	router.SetupWellKnownRoutes(app)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/.well-known/jwks.json", nil))
+	require.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+	assert.Contains(t, resp.Header.Get("Cache-Control"), "max-age")
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.NotContains(t, strings.ToLower(string(body)), `"d"`, "private keys are never published")
+
+	var set jose.JSONWebKeySet
+	require.NoError(t, json.Unmarshal(body, &set))
+	require.Len(t, set.Keys, 2)
+
+	// Other services verify tokens with the published keys alone.
+	token, err := tokens.GenerateAccessToken(testUserID, testSessionID, models.RoleUser)
+	require.NoError(t, err)
+
+	_, err = jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
+		keys := set.Key(token.Header["kid"].(string))
+		if len(keys) != 1 {
+			t.Fatalf("Expected one key for kid %v", token.Header["kid"])
+		}
+		assert.Equal(t, token.Method.Alg(), keys[0].Algorithm)
+		return keys[0].Key, nil
+	})
+	assert.NoError(t, err)
+
+	for _, key := range set.Keys {
+		assert.True(t, key.IsPublic())
+		thumbprint, err := key.Thumbprint(crypto.SHA256)
+		require.NoError(t, err)
+		assert.NotEmpty(t, thumbprint)
+	}
+}
@@ -0,0 +1,29 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"social_api/mailer"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogMailerWritesEmails(t *testing.T) {
+	dir := t.TempDir()
+	m := &mailer.LogMailer{Dir: dir, From: "no-reply@example.com"}
+
+	assert.NoError(t, m.Send(mailer.Message{To: "first@example.com", Subject: "First", Body: "Hello"}))
+	assert.NoError(t, m.Send(mailer.Message{To: "second@example.com", Subject: "Second\r\nBcc: someone@example.com", Body: "Hello again"}))
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	assert.NoError(t, err)
+	assert.Len(t, files, 2)
+
+	content, err := os.ReadFile(files[1])
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), "To: second@example.com\r\n")
+	assert.Contains(t, string(content), "Subject: SecondBcc: someone@example.com\r\n", "line breaks cannot add headers")
+	assert.Contains(t, string(content), "\r\n\r\nHello again")
+}
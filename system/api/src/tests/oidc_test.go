@@ -0,0 +1,308 @@
+package tests
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"social_api/oidc"
+	"social_api/tokens"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	mockClientID     = "social-api"
+	mockClientSecret = "client-secret"
+	mockRedirectURL  = "http://localhost:3000/api/login/oidc/mock/callback"
+)
+
+// mockAuthorization is a code the mock provider issued, with what it was
+// issued for.
+type mockAuthorization struct {
+	challenge string
+	nonce     string
+}
+
+// mockProvider is a local OpenID Connect provider that logs in every user
+// as the same account.
+type mockProvider struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	// claims are the claims of the ID tokens it issues, on top of iss, aud,
+	// iat, exp and nonce, which tamper can still change.
+	claims map[string]interface{}
+	tamper func(claims map[string]interface{})
+	// signingKey signs the ID tokens instead of the key of the JWKS when set.
+	signingKey *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]mockAuthorization
+}
+
+func newMockProvider(t *testing.T) *mockProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	m := &mockProvider{
+		key: key,
+		claims: map[string]interface{}{
+			"sub":            "248289761001",
+			"email":          "Jane.Doe@Example.com",
+			"email_verified": true,
+			"given_name":     "Jane",
+			"family_name":    "Doe",
+		},
+		codes: make(map[string]mockAuthorization),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                                m.server.URL,
+			"authorization_endpoint":                m.server.URL + "/authorize",
+			"token_endpoint":                        m.server.URL + "/token",
+			"jwks_uri":                              m.server.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+			{Key: &m.key.PublicKey, KeyID: "mock", Algorithm: string(jose.RS256), Use: "sig"},
+		}})
+	})
+	mux.HandleFunc("/token", m.token)
+
+	m.server = httptest.NewServer(mux)
+	t.Cleanup(m.server.Close)
+
+	return m
+}
+
+// authorize logs the user in at authURL, like the authorization endpoint
+// would, and returns the URL it redirects back to.
+func (m *mockProvider) authorize(t *testing.T, authURL string) *url.URL {
+	u, err := url.Parse(authURL)
+	require.NoError(t, err)
+	query := u.Query()
+
+	assert.Equal(t, m.server.URL+"/authorize", u.Scheme+"://"+u.Host+u.Path)
+	assert.Equal(t, "code", query.Get("response_type"))
+	assert.Equal(t, mockClientID, query.Get("client_id"))
+	assert.Contains(t, strings.Fields(query.Get("scope")), "openid")
+	assert.Equal(t, "S256", query.Get("code_challenge_method"))
+
+	code := base64.RawURLEncoding.EncodeToString([]byte(query.Get("state") + query.Get("nonce")))[:16]
+	m.mu.Lock()
+	m.codes[code] = mockAuthorization{challenge: query.Get("code_challenge"), nonce: query.Get("nonce")}
+	m.mu.Unlock()
+
+	redirect, err := url.Parse(query.Get("redirect_uri"))
+	require.NoError(t, err)
+	redirect.RawQuery = url.Values{"code": {code}, "state": {query.Get("state")}}.Encode()
+	return redirect
+}
+
+func (m *mockProvider) token(w http.ResponseWriter, r *http.Request) {
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientID, clientSecret = r.FormValue("client_id"), r.FormValue("client_secret")
+	}
+	if clientID != mockClientID || clientSecret != mockClientSecret {
+		http.Error(w, `{"error":"invalid_client"}`, http.StatusUnauthorized)
+		return
+	}
+
+	m.mu.Lock()
+	authorization, ok := m.codes[r.FormValue("code")]
+	delete(m.codes, r.FormValue("code"))
+	m.mu.Unlock()
+
+	verifier := sha256.Sum256([]byte(r.FormValue("code_verifier")))
+	if !ok || r.FormValue("grant_type") != "authorization_code" ||
+		base64.RawURLEncoding.EncodeToString(verifier[:]) != authorization.challenge {
+		http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	claims := map[string]interface{}{
+		"iss":   m.server.URL,
+		"aud":   mockClientID,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+		"nonce": authorization.nonce,
+	}
+	for name, value := range m.claims {
+		claims[name] = value
+	}
+	if m.tamper != nil {
+		m.tamper(claims)
+	}
+
+	signingKey := m.key
+	if m.signingKey != nil {
+		signingKey = m.signingKey
+	}
+	signer, err := jose.NewSigner(jose.SigningKey{
+		Algorithm: jose.RS256,
+		Key:       jose.JSONWebKey{Key: signingKey, KeyID: "mock"},
+	}, (&jose.SignerOptions{}).WithType("JWT"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	payload, _ := json.Marshal(claims)
+	signed, err := signer.Sign(payload)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	idToken, _ := signed.CompactSerialize()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"access_token": "mock-access-token",
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     idToken,
+	})
+}
+
+func (m *mockProvider) provider(t *testing.T) *oidc.Provider {
+	provider, err := oidc.NewProvider(context.Background(), oidc.Config{
+		Name:         "mock",
+		Issuer:       m.server.URL,
+		ClientID:     mockClientID,
+		ClientSecret: mockClientSecret,
+		RedirectURL:  mockRedirectURL,
+	})
+	require.NoError(t, err)
+	return provider
+}
+
+// login runs a login with provider at m up to the exchange of the code.
+func (m *mockProvider) login(t *testing.T, provider *oidc.Provider) (*oidc.Identity, error) {
+	request, err := oidc.NewAuthRequest()
+	require.NoError(t, err)
+
+	redirect := m.authorize(t, provider.AuthCodeURL(request))
+	assert.Equal(t, request.State, redirect.Query().Get("state"))
+
+	return provider.Exchange(context.Background(), request, redirect.Query().Get("code"))
+}
+
+func TestOIDCLogin(t *testing.T) {
+	m := newMockProvider(t)
+	provider := m.provider(t)
+
+	identity, err := m.login(t, provider)
+	require.NoError(t, err)
+	assert.Equal(t, &oidc.Identity{
+		Provider:      "mock",
+		Subject:       "248289761001",
+		Email:         "jane.doe@example.com",
+		EmailVerified: true,
+		GivenName:     "Jane",
+		FamilyName:    "Doe",
+	}, identity)
+
+	m.claims["email_verified"] = "false"
+	identity, err = m.login(t, provider)
+	require.NoError(t, err)
+	assert.False(t, identity.EmailVerified)
+}
+
+func TestOIDCLoginFailures(t *testing.T) {
+	m := newMockProvider(t)
+	provider := m.provider(t)
+
+	t.Run("wrong code verifier", func(t *testing.T) {
+		request, err := oidc.NewAuthRequest()
+		require.NoError(t, err)
+		redirect := m.authorize(t, provider.AuthCodeURL(request))
+
+		request.Verifier = strings.Repeat("a", 43)
+		_, err = provider.Exchange(context.Background(), request, redirect.Query().Get("code"))
+		assert.Error(t, err)
+	})
+
+	t.Run("code used twice", func(t *testing.T) {
+		request, err := oidc.NewAuthRequest()
+		require.NoError(t, err)
+		redirect := m.authorize(t, provider.AuthCodeURL(request))
+
+		_, err = provider.Exchange(context.Background(), request, redirect.Query().Get("code"))
+		require.NoError(t, err)
+		_, err = provider.Exchange(context.Background(), request, redirect.Query().Get("code"))
+		assert.Error(t, err)
+	})
+
+	tampered := map[string]func(claims map[string]interface{}){
+		"other nonce":    func(claims map[string]interface{}) { claims["nonce"] = "other" },
+		"other audience": func(claims map[string]interface{}) { claims["aud"] = "other-client" },
+		"other issuer":   func(claims map[string]interface{}) { claims["iss"] = "https://evil.example.com" },
+		"expired":        func(claims map[string]interface{}) { claims["exp"] = time.Now().Add(-time.Minute).Unix() },
+	}
+	for name, tamper := range tampered {
+		t.Run(name, func(t *testing.T) {
+			m.tamper = tamper
+			defer func() { m.tamper = nil }()
+
+			_, err := m.login(t, provider)
+			assert.Error(t, err)
+		})
+	}
+
+	t.Run("other signing key", func(t *testing.T) {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+		m.signingKey = key
+		defer func() { m.signingKey = nil }()
+
+		_, err = m.login(t, provider)
+		assert.Error(t, err)
+	})
+
+	t.Run("issuer mismatch in discovery", func(t *testing.T) {
+		_, err := oidc.NewProvider(context.Background(), oidc.Config{
+			Name:     "mock",
+			Issuer:   m.server.URL + "/",
+			ClientID: mockClientID,
+		})
+		assert.Error(t, err)
+	})
+}
+
+func TestOIDCLoginTokens(t *testing.T) {
+	useTestTokens(t)
+
+	token, err := tokens.GenerateOIDCLoginToken("mock", "state", "nonce", "verifier")
+	require.NoError(t, err)
+
+	claims, err := tokens.ParseOIDCLoginToken(token, "mock")
+	require.NoError(t, err)
+	assert.Equal(t, "state", claims.State)
+	assert.Equal(t, "nonce", claims.Nonce)
+	assert.Equal(t, "verifier", claims.Verifier)
+
+	_, err = tokens.ParseOIDCLoginToken(token, "other")
+	assert.Error(t, err)
+
+	mfaToken, err := tokens.GeneratePurposeToken(tokens.PurposeMFA, "user", "user@example.com", time.Minute)
+	require.NoError(t, err)
+	_, err = tokens.ParseOIDCLoginToken(mfaToken, "mock")
+	assert.Error(t, err)
+}
@@ -0,0 +1,294 @@
+package tests
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+
+	"social_api/passkeys"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/go-webauthn/webauthn/protocol/webauthncose"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	passkeyRPID   = "localhost"
+	passkeyOrigin = "http://localhost:3000"
+)
+
+// softAuthenticator stands in for a platform authenticator holding one
+// passkey. It answers the options the API returns the way a browser passes
+// them on, as JSON.
+type softAuthenticator struct {
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+	userHandle   []byte
+	counter      uint32
+	// flags are the flags of the authenticator data. They default to user
+	// present and user verified.
+	flags byte
+}
+
+func newSoftAuthenticator(t *testing.T) *softAuthenticator {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	credentialID := make([]byte, 16)
+	_, err = rand.Read(credentialID)
+	require.NoError(t, err)
+
+	return &softAuthenticator{key: key, credentialID: credentialID, flags: 0x01 | 0x04}
+}
+
+// publicKeyOptions is what the authenticator reads of the options.
+type publicKeyOptions struct {
+	PublicKey struct {
+		Challenge string `json:"challenge"`
+		RP        struct {
+			ID string `json:"id"`
+		} `json:"rp"`
+		RPID string `json:"rpId"`
+		User struct {
+			ID string `json:"id"`
+		} `json:"user"`
+		ExcludeCredentials []struct {
+			ID string `json:"id"`
+		} `json:"excludeCredentials"`
+	} `json:"publicKey"`
+}
+
+func readOptions(t *testing.T, options interface{}) publicKeyOptions {
+	data, err := json.Marshal(options)
+	require.NoError(t, err)
+
+	var read publicKeyOptions
+	require.NoError(t, json.Unmarshal(data, &read))
+	return read
+}
+
+func (a *softAuthenticator) authenticatorData(rpID string, attested []byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(rpID))
+
+	flags := a.flags
+	if attested != nil {
+		flags |= 0x40
+	}
+
+	data := append(rpIDHash[:], flags)
+	data = binary.BigEndian.AppendUint32(data, a.counter)
+	return append(data, attested...)
+}
+
+func clientData(ceremony, challenge, origin string) []byte {
+	data, _ := json.Marshal(map[string]string{
+		"type":      ceremony,
+		"challenge": challenge,
+		"origin":    origin,
+	})
+	return data
+}
+
+// create answers the options of navigator.credentials.create.
+func (a *softAuthenticator) create(t *testing.T, creation *protocol.CredentialCreation, origin string) []byte {
+	options := readOptions(t, creation)
+
+	userHandle, err := base64.RawURLEncoding.DecodeString(options.PublicKey.User.ID)
+	require.NoError(t, err)
+	a.userHandle = userHandle
+
+	publicKey, err := webauthncbor.Marshal(webauthncose.EC2PublicKeyData{
+		PublicKeyData: webauthncose.PublicKeyData{
+			KeyType:   int64(webauthncose.EllipticKey),
+			Algorithm: int64(webauthncose.AlgES256),
+		},
+		Curve:  1,
+		XCoord: a.key.PublicKey.X.FillBytes(make([]byte, 32)),
+		YCoord: a.key.PublicKey.Y.FillBytes(make([]byte, 32)),
+	})
+	require.NoError(t, err)
+
+	attested := make([]byte, 16)
+	attested = binary.BigEndian.AppendUint16(attested, uint16(len(a.credentialID)))
+	attested = append(attested, a.credentialID...)
+	attested = append(attested, publicKey...)
+
+	attestationObject, err := webauthncbor.Marshal(map[string]interface{}{
+		"fmt":      "none",
+		"attStmt":  map[string]interface{}{},
+		"authData": a.authenticatorData(options.PublicKey.RP.ID, attested),
+	})
+	require.NoError(t, err)
+
+	return a.credential(t, map[string]string{
+		"clientDataJSON":    base64.RawURLEncoding.EncodeToString(clientData("webauthn.create", options.PublicKey.Challenge, origin)),
+		"attestationObject": base64.RawURLEncoding.EncodeToString(attestationObject),
+	})
+}
+
+// get answers the options of navigator.credentials.get.
+func (a *softAuthenticator) get(t *testing.T, assertion *protocol.CredentialAssertion, origin string) []byte {
+	options := readOptions(t, assertion)
+
+	a.counter++
+	authenticatorData := a.authenticatorData(options.PublicKey.RPID, nil)
+	clientDataJSON := clientData("webauthn.get", options.PublicKey.Challenge, origin)
+
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	signed := sha256.Sum256(append(append([]byte{}, authenticatorData...), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, signed[:])
+	require.NoError(t, err)
+
+	return a.credential(t, map[string]string{
+		"clientDataJSON":    base64.RawURLEncoding.EncodeToString(clientDataJSON),
+		"authenticatorData": base64.RawURLEncoding.EncodeToString(authenticatorData),
+		"signature":         base64.RawURLEncoding.EncodeToString(signature),
+		"userHandle":        base64.RawURLEncoding.EncodeToString(a.userHandle),
+	})
+}
+
+func (a *softAuthenticator) credential(t *testing.T, response map[string]string) []byte {
+	id := base64.RawURLEncoding.EncodeToString(a.credentialID)
+	data, err := json.Marshal(map[string]interface{}{
+		"id":       id,
+		"rawId":    id,
+		"type":     "public-key",
+		"response": response,
+	})
+	require.NoError(t, err)
+	return data
+}
+
+func newPasskeyUser() *passkeys.User {
+	return &passkeys.User{
+		ID:          uuid.NewString(),
+		Name:        "johndoe",
+		DisplayName: "John Doe",
+	}
+}
+
+// registerPasskey runs a registration ceremony of authenticator for user and
+// adds the new credential to user.
+func registerPasskey(t *testing.T, w *webauthn.WebAuthn, user *passkeys.User, authenticator *softAuthenticator) {
+	creation, session, err := passkeys.BeginRegistration(w, user)
+	require.NoError(t, err)
+
+	response, err := protocol.ParseCredentialCreationResponseBody(bytes.NewReader(authenticator.create(t, creation, passkeyOrigin)))
+	require.NoError(t, err)
+	// The API finds the ceremony of an answer by its challenge.
+	assert.Equal(t, session.Challenge, response.Response.CollectedClientData.Challenge)
+
+	credential, err := passkeys.FinishRegistration(w, user, *session, response)
+	require.NoError(t, err)
+	assert.Equal(t, authenticator.credentialID, credential.ID)
+
+	user.Credentials = append(user.Credentials, *credential)
+}
+
+// loginWithPasskey runs a login ceremony, answered by authenticator from
+// origin, against the users of users.
+func loginWithPasskey(t *testing.T, w *webauthn.WebAuthn, users []*passkeys.User, authenticator *softAuthenticator, origin string) (*passkeys.User, *webauthn.Credential, error) {
+	assertion, session, err := passkeys.BeginLogin(w)
+	require.NoError(t, err)
+
+	response, err := protocol.ParseCredentialRequestResponseBody(bytes.NewReader(authenticator.get(t, assertion, origin)))
+	require.NoError(t, err)
+	assert.Equal(t, session.Challenge, response.Response.CollectedClientData.Challenge)
+
+	lookup := func(credentialID, userHandle []byte) (*passkeys.User, error) {
+		for _, user := range users {
+			if string(userHandle) == user.ID {
+				return user, nil
+			}
+		}
+		return nil, nil
+	}
+
+	return passkeys.FinishLogin(w, *session, response, lookup)
+}
+
+func TestPasskeyRegistrationAndLogin(t *testing.T) {
+	w, err := passkeys.New(passkeyRPID, "Social API", []string{passkeyOrigin})
+	require.NoError(t, err)
+
+	user := newPasskeyUser()
+	authenticator := newSoftAuthenticator(t)
+	registerPasskey(t, w, user, authenticator)
+	assert.Equal(t, []byte(user.ID), authenticator.userHandle)
+
+	other := newPasskeyUser()
+	registerPasskey(t, w, other, newSoftAuthenticator(t))
+
+	loggedIn, credential, err := loginWithPasskey(t, w, []*passkeys.User{other, user}, authenticator, passkeyOrigin)
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, loggedIn.ID)
+	assert.Equal(t, authenticator.credentialID, credential.ID)
+	assert.Equal(t, authenticator.counter, credential.Authenticator.SignCount)
+
+	// A second registration leaves out the authenticator that already holds
+	// a passkey of the user.
+	creation, _, err := passkeys.BeginRegistration(w, user)
+	require.NoError(t, err)
+	excluded := readOptions(t, creation).PublicKey.ExcludeCredentials
+	require.Len(t, excluded, 1)
+	assert.Equal(t, base64.RawURLEncoding.EncodeToString(authenticator.credentialID), excluded[0].ID)
+}
+
+func TestPasskeyLoginFailures(t *testing.T) {
+	w, err := passkeys.New(passkeyRPID, "Social API", []string{passkeyOrigin})
+	require.NoError(t, err)
+
+	user := newPasskeyUser()
+	authenticator := newSoftAuthenticator(t)
+	registerPasskey(t, w, user, authenticator)
+	users := []*passkeys.User{user}
+
+	t.Run("other origin", func(t *testing.T) {
+		_, _, err := loginWithPasskey(t, w, users, authenticator, "https://evil.example.com")
+		assert.Error(t, err)
+	})
+
+	t.Run("unknown user", func(t *testing.T) {
+		_, _, err := loginWithPasskey(t, w, nil, authenticator, passkeyOrigin)
+		assert.Error(t, err)
+	})
+
+	t.Run("other key", func(t *testing.T) {
+		impostor := newSoftAuthenticator(t)
+		impostor.credentialID = authenticator.credentialID
+		impostor.userHandle = authenticator.userHandle
+
+		_, _, err := loginWithPasskey(t, w, users, impostor, passkeyOrigin)
+		assert.Error(t, err)
+	})
+
+	t.Run("user not verified", func(t *testing.T) {
+		unverified := *authenticator
+		unverified.flags = 0x01
+
+		_, _, err := loginWithPasskey(t, w, users, &unverified, passkeyOrigin)
+		assert.Error(t, err)
+	})
+
+	t.Run("cloned authenticator", func(t *testing.T) {
+		_, credential, err := loginWithPasskey(t, w, users, authenticator, passkeyOrigin)
+		require.NoError(t, err)
+		user.Credentials[0] = *credential
+
+		clone := *authenticator
+		clone.counter = 0
+
+		_, _, err = loginWithPasskey(t, w, users, &clone, passkeyOrigin)
+		assert.ErrorIs(t, err, passkeys.ErrClonedAuthenticator)
+	})
+}
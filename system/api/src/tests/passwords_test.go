@@ -0,0 +1,63 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"social_api/passwords"
+	"social_api/schemas"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPasswordPolicy(t *testing.T) {
+	policy := passwords.Policy{MinLength: 8, MaxLength: passwords.MaxLength}
+
+	assert.NoError(t, policy.Check("correct horse battery"))
+	assert.Error(t, policy.Check("short"))
+	assert.Error(t, policy.Check(strings.Repeat("a", passwords.MaxLength+1)), "bcrypt ignores what goes past 72 bytes")
+	assert.Error(t, policy.Check("Password123"), "breached passwords are refused ignoring case")
+
+	policy.RequireUpper = true
+	policy.RequireDigit = true
+	policy.RequireSymbol = true
+	assert.Error(t, policy.Check("lowercase only"))
+	assert.Error(t, policy.Check("Uppercase only"))
+	assert.Error(t, policy.Check("Uppercase4digits"))
+	assert.NoError(t, policy.Check("Upper, digit 4 and symbol"))
+}
+
+func TestPasswordPolicyFromEnvironment(t *testing.T) {
+	previous := passwords.Default
+	t.Cleanup(func() { passwords.Default = previous })
+
+	list := filepath.Join(t.TempDir(), "breached.txt")
+	assert.NoError(t, os.WriteFile(list, []byte("# comment\nHunter2Hunter2\n"), 0o644))
+
+	t.Setenv("PASSWORD_MIN_LENGTH", "10")
+	t.Setenv("PASSWORD_REQUIRE_DIGIT", "true")
+	t.Setenv("BREACHED_PASSWORDS_FILE", list)
+	assert.NoError(t, passwords.Init())
+
+	assert.Equal(t, 10, passwords.Default.MinLength)
+	assert.True(t, passwords.Default.RequireDigit)
+	assert.True(t, passwords.IsBreached("hunter2hunter2"))
+
+	// Registration follows the same policy.
+	request := schemas.RegistrationRequest{
+		Username:  "someone",
+		Email:     "someone@example.com",
+		Password:  "no digits here",
+		Firstname: "Some",
+		Lastname:  "One",
+	}
+	assert.Error(t, schemas.Validate(request))
+
+	request.Password = "1 digit is enough"
+	assert.NoError(t, schemas.Validate(request))
+
+	request.Password = "Hunter2Hunter2"
+	assert.Error(t, schemas.Validate(request))
+}
@@ -0,0 +1,180 @@
+package tests
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"social_api/middlewares"
+	"social_api/models"
+	"social_api/ratelimit"
+	"social_api/tokens"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenBucket(t *testing.T) {
+	policy := ratelimit.Policy{Name: "test", Limit: 3, Period: 3 * time.Second}
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	bucket := &ratelimit.Bucket{}
+
+	for remaining := 2; remaining >= 0; remaining-- {
+		result := policy.Take(bucket, now)
+		assert.True(t, result.Allowed, "a new bucket allows a burst")
+		assert.Equal(t, remaining, result.Remaining)
+	}
+
+	result := policy.Take(bucket, now)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, time.Second, result.RetryAfter)
+	assert.Equal(t, 3*time.Second, result.Reset)
+
+	// One token is back every second.
+	result = policy.Take(bucket, now.Add(1500*time.Millisecond))
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+
+	result = policy.Take(bucket, now.Add(1600*time.Millisecond))
+	assert.False(t, result.Allowed)
+	assert.InDelta(t, 400*time.Millisecond, result.RetryAfter, float64(time.Millisecond))
+
+	// The bucket never holds more than the limit.
+	result = policy.Take(bucket, now.Add(time.Hour))
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 2, result.Remaining)
+}
+
+func TestMemoryStoreKeepsBucketsApart(t *testing.T) {
+	store := ratelimit.NewMemoryStore()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	store.Now = func() time.Time { return now }
+	policy := ratelimit.Policy{Name: "test", Limit: 1, Period: time.Minute}
+
+	result, err := store.Take("first", policy)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	result, err = store.Take("first", policy)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+
+	result, err = store.Take("second", policy)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+
+	now = now.Add(time.Minute)
+	result, err = store.Take("first", policy)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	useTestTokens(t)
+
+	previous := ratelimit.Default
+	store := ratelimit.NewMemoryStore()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	store.Now = func() time.Time { return now }
+	ratelimit.Default = store
+	t.Cleanup(func() { ratelimit.Default = previous })
+
+	global := &ratelimit.Policy{Name: "global", Limit: 5, Period: time.Minute}
+	strict := &ratelimit.Policy{Name: "strict", Limit: 2, Period: time.Minute}
+	off := &ratelimit.Policy{Name: "off", Limit: 0, Period: time.Minute}
+
+	app := fiber.New()
+	app.Use(middlewares.RateLimit(global))
+	ok := func(c *fiber.Ctx) error { return c.SendStatus(200) }
+	app.Post("/strict", middlewares.RateLimit(strict), ok)
+	app.Get("/loose", ok)
+	app.Get("/off", middlewares.RateLimit(off), ok)
+
+	send := func(method, path, session string) (int, map[string]string) {
+		req := httptest.NewRequest(method, path, nil)
+		if session != "" {
+			req.Header.Set("session", session)
+		}
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		headers := map[string]string{}
+		for _, name := range []string{"RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy", "Retry-After"} {
+			headers[name] = resp.Header.Get(name)
+		}
+		return resp.StatusCode, headers
+	}
+
+	status, headers := send("POST", "/strict", "")
+	assert.Equal(t, 200, status)
+	assert.Equal(t, "2", headers["RateLimit-Limit"], "the tightest policy is announced")
+	assert.Equal(t, "1", headers["RateLimit-Remaining"])
+	assert.Equal(t, "30", headers["RateLimit-Reset"])
+	assert.Equal(t, "2;w=60", headers["RateLimit-Policy"])
+	assert.Empty(t, headers["Retry-After"])
+
+	status, _ = send("POST", "/strict", "")
+	assert.Equal(t, 200, status)
+
+	status, headers = send("POST", "/strict", "")
+	assert.Equal(t, 429, status)
+	assert.Equal(t, "0", headers["RateLimit-Remaining"])
+	assert.Equal(t, "30", headers["Retry-After"])
+
+	// Other routes only follow the global policy, which counted every request.
+	status, headers = send("GET", "/loose", "")
+	assert.Equal(t, 200, status)
+	assert.Equal(t, "5", headers["RateLimit-Limit"])
+	assert.Equal(t, "1", headers["RateLimit-Remaining"])
+
+	// Authenticated requests have their own buckets, whatever their IP.
+	session, err := tokens.GenerateAccessToken(testUserID, testSessionID, models.RoleUser)
+	require.NoError(t, err)
+	status, headers = send("POST", "/strict", session)
+	assert.Equal(t, 200, status)
+	assert.Equal(t, "1", headers["RateLimit-Remaining"])
+
+	// An invalid token counts against the IP.
+	status, _ = send("POST", "/strict", "garbage")
+	assert.Equal(t, 429, status)
+
+	status, headers = send("GET", "/off", session)
+	assert.Equal(t, 200, status, "a limit of 0 is off")
+	assert.Equal(t, "5", headers["RateLimit-Limit"])
+	status, headers = send("GET", "/off", "")
+	assert.Equal(t, 429, status, "the global policy still applies")
+	assert.Equal(t, "0", headers["RateLimit-Remaining"])
+
+	now = now.Add(time.Minute)
+	status, _ = send("POST", "/strict", "")
+	assert.Equal(t, 200, status, "the buckets fill up again")
+}
+
+func TestRateLimitByIPIgnoresTokens(t *testing.T) {
+	useTestTokens(t)
+
+	previous := ratelimit.Default
+	ratelimit.Default = ratelimit.NewMemoryStore()
+	t.Cleanup(func() { ratelimit.Default = previous })
+
+	policy := &ratelimit.Policy{Name: "login", Limit: 2, Period: time.Minute}
+	app := fiber.New()
+	app.Post("/login", middlewares.RateLimitByIP(policy), func(c *fiber.Ctx) error { return c.SendStatus(200) })
+
+	login := func(userID string) int {
+		req := httptest.NewRequest("POST", "/login", nil)
+		if userID != "" {
+			session, err := tokens.GenerateAccessToken(userID, testSessionID, models.RoleUser)
+			require.NoError(t, err)
+			req.Header.Set("session", session)
+		}
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		return resp.StatusCode
+	}
+
+	assert.Equal(t, 200, login(""))
+	assert.Equal(t, 200, login(testUserID))
+	// The tokens of other accounts do not earn more attempts from the same IP.
+	assert.Equal(t, 429, login("0d9c6f5e-7b7e-4f0e-9a53-2b8f1c4d6e7a"))
+	assert.Equal(t, 429, login(""))
+}
@@ -0,0 +1,74 @@
+package tests
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"social_api/middlewares"
+	"social_api/models"
+	router "social_api/router/Admin"
+	"social_api/tokens"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoles(t *testing.T) {
+	assert.True(t, models.RoleAtLeast(models.RoleAdmin, models.RoleModerator))
+	assert.True(t, models.RoleAtLeast(models.RoleModerator, models.RoleModerator))
+	assert.False(t, models.RoleAtLeast(models.RoleUser, models.RoleModerator))
+	assert.False(t, models.RoleAtLeast("", models.RoleUser), "tokens without a role grant nothing")
+	assert.False(t, models.RoleAtLeast("superuser", models.RoleUser), "unknown roles grant nothing")
+
+	assert.True(t, models.RoleAbove(models.RoleAdmin, models.RoleModerator))
+	assert.True(t, models.RoleAbove(models.RoleModerator, models.RoleUser))
+	assert.False(t, models.RoleAbove(models.RoleModerator, models.RoleModerator), "peers cannot moderate each other")
+	assert.False(t, models.RoleAbove(models.RoleModerator, models.RoleAdmin))
+}
+
+func TestAccessTokensCarryTheRole(t *testing.T) {
+	useTestTokens(t)
+
+	token, err := tokens.GenerateAccessToken(testUserID, testSessionID, models.RoleModerator)
+	require.NoError(t, err)
+
+	claims, err := tokens.ParseAccessToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, models.RoleModerator, claims.Role)
+}
+
+func TestRequireRoleNeedsAuth(t *testing.T) {
+	app := fiber.New()
+	app.Get("/moderation", middlewares.RequireRole(models.RoleModerator), func(c *fiber.Ctx) error {
+		return c.SendStatus(200)
+	})
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/moderation", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode, "requests that were not authenticated have no role")
+}
+
+func TestAdminRoutesNeedAuth(t *testing.T) {
+	useTestTokens(t)
+
+	app := fiber.New()
+	router.SetupAdminRoutes(app)
+
+	for _, route := range []struct{ method, path string }{
+		{"GET", "/api/admin/users"},
+		{"POST", "/api/admin/users/" + testUserID + "/suspend"},
+		{"POST", "/api/admin/users/" + testUserID + "/unsuspend"},
+		{"POST", "/api/admin/users/" + testUserID + "/force-password-reset"},
+	} {
+		resp, err := app.Test(httptest.NewRequest(route.method, route.path, nil))
+		require.NoError(t, err)
+		assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode, route.path)
+
+		req := httptest.NewRequest(route.method, route.path, nil)
+		req.Header.Set("session", "garbage")
+		resp, err = app.Test(req)
+		require.NoError(t, err)
+		assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode, route.path)
+	}
+}
@@ -2,14 +2,26 @@ package tests
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"io"
 	"net/http"
+	"net/http/httptest"
+	"os"
 	"strings"
 	"testing"
 
+	"social_api/db"
+	"social_api/models"
+	"social_api/oidc"
+	users "social_api/router/Users"
+	"social_api/tokens"
+
 	"github.com/Pallinder/go-randomdata"
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v4/pgxpool"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestSetupAuthenticationRoutes(t *testing.T) {
@@ -17,12 +29,12 @@ func TestSetupAuthenticationRoutes(t *testing.T) {
 
 	// Generating random user data for registration
 	username := randomdata.SillyName()
-	password := randomdata.SillyName()
+	password := randomdata.SillyName() + randomdata.StringNumber(2, "")
 	email := randomdata.Email()
 	firstname := randomdata.FirstName(randomdata.Male)
 	lastname := randomdata.LastName()
 	username2 := randomdata.SillyName()
-	passwd2 := randomdata.SillyName()
+	passwd2 := randomdata.SillyName() + randomdata.StringNumber(2, "")
 
 	// Register user
 	reqData := map[string]string{
@@ -146,15 +158,26 @@ func TestSetupAuthenticationRoutes(t *testing.T) {
 		body         string
 		expectedCode int
 	}{
-		{"POST", "/api/logout", "", 200},
 		{"POST", "/api/login", `{"Username":"` + username + `", "Password":"` + password + `"}`, 200},
 		{"GET", "/api/profile", "", 200},
 		{"GET", "/api/profile/6a689342-6b5f-4a0e-a641-0c0d8a06b8cc", "", 200},
 		{"POST", "/api/update-username", `{"username": "` + username2 + `"}`, 200},
-		{"POST", "/api/update-password", `{"password": "` + passwd2 + `"}`, 200},
+		{"POST", "/api/update-password", `{"currentPassword": "` + password + `", "password": "` + passwd2 + `"}`, 200},
+		{"POST", "/api/update-password", `{"currentPassword": "` + passwd2 + `", "password": "` + password + `"}`, 400},
 		{"POST", "/api/followuser/6a689342-6b5f-4a0e-a641-0c0d8a06b8cc", "", 200},
 		{"POST", "/api/unfollowuser/6a689342-6b5f-4a0e-a641-0c0d8a06b8cc", "", 200},
 		{"GET", "/api/getfollowers/" + userID, "", 200},
+		{"GET", "/api/users/" + userID + "/following", "", 200},
+		{"POST", "/api/posts", `{"title": "Hello", "description": "My first post"}`, 201},
+		{"GET", "/api/posts?author=" + userID, "", 200},
+		{"GET", "/api/sessions", "", 200},
+		{"GET", "/api/passkeys", "", 200},
+		{"POST", "/api/login/passkey/begin", "", 200},
+		{"GET", "/api/login/oidc/unknown", "", 404},
+		{"GET", "/.well-known/jwks.json", "", 200},
+		{"GET", "/api/admin/users", "", 403},
+		{"POST", "/api/logout", "", 200},
+		{"GET", "/api/profile", "", 401},
 	}
 
 	// Iterating over each test case to verify API routes
@@ -189,3 +212,56 @@ func TestSetupAuthenticationRoutes(t *testing.T) {
 		assert.Equal(t, tt.expectedCode, resp.StatusCode, "Expected status code to be %d for route %s", tt.expectedCode, tt.route)
 	}
 }
+
+func TestOIDCSignupRole(t *testing.T) {
+	dbURL := os.Getenv("POSTGRES_URL")
+	if dbURL == "" {
+		t.Skip("POSTGRES_URL is not set")
+	}
+
+	pool, err := pgxpool.Connect(context.Background(), dbURL)
+	require.NoError(t, err)
+	previous := db.Pool
+	db.Pool = pool
+	t.Cleanup(func() {
+		db.Pool = previous
+		pool.Close()
+	})
+
+	useTestTokens(t)
+
+	// A subject and email nobody has yet, so the login signs a new user up.
+	m := newMockProvider(t)
+	m.claims["sub"] = randomdata.StringNumber(12, "")
+	m.claims["email"] = randomdata.Email()
+	oidc.Register(m.provider(t))
+
+	app := fiber.New()
+	users.SetupOIDCRoutes(app)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/api/login/oidc/mock", nil))
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusFound, resp.StatusCode)
+
+	redirect := m.authorize(t, resp.Header.Get("Location"))
+	req := httptest.NewRequest("GET", redirect.RequestURI(), nil)
+	for _, cookie := range resp.Cookies() {
+		req.AddCookie(cookie)
+	}
+	resp, err = app.Test(req)
+	require.NoError(t, err)
+	bodyBytes, _ := io.ReadAll(resp.Body)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode, string(bodyBytes))
+
+	claims, err := tokens.ParseAccessToken(resp.Header.Get("Session"))
+	require.NoError(t, err)
+	assert.Equal(t, models.RoleUser, claims.Role, "users who sign up with a provider start as users")
+
+	var loginData struct {
+		User struct {
+			Role string `json:"role"`
+		} `json:"user"`
+	}
+	require.NoError(t, json.Unmarshal(bodyBytes, &loginData))
+	assert.Equal(t, models.RoleUser, loginData.User.Role)
+}
@@ -0,0 +1,318 @@
+package tests
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"social_api/libs"
+	"social_api/middlewares"
+	"social_api/models"
+	"social_api/tokens"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testUserID    = "6a689342-6b5f-4a0e-a641-0c0d8a06b8cc"
+	testSessionID = "0f3c2a59-4a8e-4c57-9a43-6a3e4a1c9b10"
+)
+
+// useTestTokens makes the API issue tokens with a new Ed25519 key and a test
+// secret, until the test ends.
+func useTestTokens(t *testing.T) *tokens.Issuer {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Error generating key: %v", err)
+	}
+	key, err := tokens.NewKey(private, nil)
+	if err != nil {
+		t.Fatalf("Error generating key: %v", err)
+	}
+	keyring, err := tokens.NewKeyring(key)
+	if err != nil {
+		t.Fatalf("Error generating keyring: %v", err)
+	}
+
+	previous := tokens.Default
+	t.Cleanup(func() { tokens.Default = previous })
+
+	tokens.Default = &tokens.Issuer{
+		Name:      "http://localhost:3000",
+		Audience:  "http://localhost:3000",
+		ClockSkew: tokens.DefaultClockSkew,
+		Keyring:   keyring,
+		Secret:    []byte("test-secret"),
+	}
+	return tokens.Default
+}
+
+// accessClaims are valid claims of an access token of issuer, issued at
+// issuedAt.
+func accessClaims(issuer *tokens.Issuer, issuedAt time.Time) *tokens.AccessClaims {
+	return &tokens.AccessClaims{
+		UserID:    testUserID,
+		SessionID: testSessionID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    issuer.Name,
+			Audience:  jwt.ClaimStrings{issuer.Audience},
+			ExpiresAt: jwt.NewNumericDate(issuedAt.Add(tokens.AccessTokenLifetime)),
+			NotBefore: jwt.NewNumericDate(issuedAt),
+			IssuedAt:  jwt.NewNumericDate(issuedAt),
+		},
+	}
+}
+
+func TestSecretTokensAreRandomAndHashed(t *testing.T) {
+	first, firstHash, err := libs.GenerateSecretToken()
+	assert.NoError(t, err)
+	second, _, err := libs.GenerateSecretToken()
+	assert.NoError(t, err)
+
+	assert.NotEqual(t, first, second)
+	assert.NotContains(t, firstHash, first)
+	assert.Equal(t, firstHash, libs.HashSecretToken(first))
+	assert.Len(t, firstHash, 64)
+}
+
+func TestAuthMiddlewares(t *testing.T) {
+	issuer := useTestTokens(t)
+
+	app := fiber.New()
+	handler := func(c *fiber.Ctx) error {
+		return c.SendString(middlewares.AuthUserID(c))
+	}
+	app.Get("/required", middlewares.RequireAuth, handler)
+	app.Get("/optional", middlewares.OptionalAuth, handler)
+
+	expired, err := issuer.Keyring.Sign(accessClaims(issuer, time.Now().Add(-time.Hour)))
+	assert.NoError(t, err)
+
+	// Tokens issued before sessions existed cannot be revoked, so they are refused.
+	claims := accessClaims(issuer, time.Now())
+	claims.SessionID = ""
+	withoutSession, err := issuer.Keyring.Sign(claims)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		route        string
+		token        string
+		expectedCode int
+		expectedBody string
+	}{
+		{"/required", "", 401, "Missing authorization header."},
+		{"/optional", "", 200, ""},
+		{"/required", expired, 401, "Token expired"},
+		{"/optional", expired, 401, "Token expired"},
+		{"/required", withoutSession, 401, "Invalid token"},
+		{"/optional", "garbage", 401, "Invalid token"},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", tt.route, nil)
+		req.Header.Set("session", tt.token)
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.expectedCode, resp.StatusCode, "route %s", tt.route)
+		assert.Empty(t, resp.Header.Get("session"), "tokens are never renewed by the middlewares")
+
+		body, _ := io.ReadAll(resp.Body)
+		if tt.expectedCode == 200 {
+			assert.Empty(t, string(body), "anonymous requests have no user")
+		} else {
+			assert.Contains(t, string(body), tt.expectedBody)
+		}
+	}
+}
+
+func TestTokensCarrySessionAndID(t *testing.T) {
+	issuer := useTestTokens(t)
+
+	first, err := tokens.GenerateAccessToken(testUserID, testSessionID, models.RoleUser)
+	assert.NoError(t, err)
+	second, err := tokens.GenerateAccessToken(testUserID, testSessionID, models.RoleUser)
+	assert.NoError(t, err)
+
+	firstClaims, err := tokens.ParseAccessToken(first)
+	require.NoError(t, err)
+	secondClaims, err := tokens.ParseAccessToken(second)
+	require.NoError(t, err)
+
+	assert.Equal(t, testSessionID, firstClaims.SessionID)
+	assert.NotEmpty(t, firstClaims.ID)
+	assert.NotEqual(t, firstClaims.ID, secondClaims.ID)
+
+	assert.Equal(t, issuer.Name, firstClaims.Issuer)
+	assert.Equal(t, jwt.ClaimStrings{issuer.Audience}, firstClaims.Audience)
+	assert.NotNil(t, firstClaims.NotBefore)
+	assert.NotNil(t, firstClaims.IssuedAt)
+}
+
+func TestTokenValidation(t *testing.T) {
+	issuer := useTestTokens(t)
+	now := time.Now()
+
+	tests := []struct {
+		name   string
+		change func(claims *tokens.AccessClaims)
+		valid  bool
+	}{
+		{"valid", func(claims *tokens.AccessClaims) {}, true},
+		{"other issuer", func(claims *tokens.AccessClaims) { claims.Issuer = "https://evil.example.com" }, false},
+		{"without issuer", func(claims *tokens.AccessClaims) { claims.Issuer = "" }, false},
+		{"other audience", func(claims *tokens.AccessClaims) { claims.Audience = jwt.ClaimStrings{"other-api"} }, false},
+		{"without audience", func(claims *tokens.AccessClaims) { claims.Audience = nil }, false},
+		{"among audiences", func(claims *tokens.AccessClaims) {
+			claims.Audience = jwt.ClaimStrings{"other-api", issuer.Audience}
+		}, true},
+		{"without expiration", func(claims *tokens.AccessClaims) { claims.ExpiresAt = nil }, false},
+		{"expired within clock skew", func(claims *tokens.AccessClaims) {
+			claims.ExpiresAt = jwt.NewNumericDate(now.Add(-issuer.ClockSkew / 2))
+		}, true},
+		{"expired beyond clock skew", func(claims *tokens.AccessClaims) {
+			claims.ExpiresAt = jwt.NewNumericDate(now.Add(-2 * issuer.ClockSkew))
+		}, false},
+		{"not before within clock skew", func(claims *tokens.AccessClaims) {
+			claims.NotBefore = jwt.NewNumericDate(now.Add(issuer.ClockSkew / 2))
+		}, true},
+		{"not before beyond clock skew", func(claims *tokens.AccessClaims) {
+			claims.NotBefore = jwt.NewNumericDate(now.Add(2 * issuer.ClockSkew))
+		}, false},
+		{"issued in the future", func(claims *tokens.AccessClaims) {
+			claims.IssuedAt = jwt.NewNumericDate(now.Add(time.Hour))
+		}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := accessClaims(issuer, now)
+			tt.change(claims)
+			token, err := issuer.Keyring.Sign(claims)
+			require.NoError(t, err)
+
+			_, err = tokens.ParseAccessToken(token)
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+
+	t.Run("other deployment", func(t *testing.T) {
+		token, err := tokens.GenerateAccessToken(testUserID, testSessionID, models.RoleUser)
+		require.NoError(t, err)
+
+		// Same keys, but another issuer and audience.
+		other := *issuer
+		other.Name = "https://staging.example.com"
+		other.Audience = "https://staging.example.com"
+		tokens.Default = &other
+
+		_, err = tokens.ParseAccessToken(token)
+		assert.Error(t, err)
+	})
+}
+
+// TestForgedTokens makes sure neither kind of token can be forged by picking
+// its algorithm: unsigned tokens, access tokens signed with the HMAC secret of
+// purpose tokens or with the public key as HMAC secret, and purpose tokens
+// signed with the keyring.
+func TestForgedTokens(t *testing.T) {
+	issuer := useTestTokens(t)
+	key := issuer.Keyring.JWKS().Keys[0]
+
+	der, err := x509.MarshalPKIXPublicKey(key.Key)
+	require.NoError(t, err)
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+
+	sign := func(method jwt.SigningMethod, secret interface{}, claims jwt.Claims) string {
+		token := jwt.NewWithClaims(method, claims)
+		token.Header["kid"] = key.KeyID
+		signed, err := token.SignedString(secret)
+		require.NoError(t, err)
+		return signed
+	}
+
+	access := accessClaims(issuer, time.Now())
+	for name, token := range map[string]string{
+		"alg none":                  sign(jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, access),
+		"HS256 with the secret":     sign(jwt.SigningMethodHS256, issuer.Secret, access),
+		"HS256 with the public key": sign(jwt.SigningMethodHS256, publicPEM, access),
+		"HS256 with the raw key":    sign(jwt.SigningMethodHS256, []byte(key.Key.(ed25519.PublicKey)), access),
+	} {
+		_, err := tokens.ParseAccessToken(token)
+		assert.Error(t, err, name)
+	}
+
+	purpose := &tokens.PurposeClaims{
+		Email: "someone@example.com",
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    issuer.Name,
+			Subject:   testUserID,
+			Audience:  jwt.ClaimStrings{tokens.PurposeVerifyEmail},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	}
+	signedWithKeyring, err := issuer.Keyring.Sign(purpose)
+	require.NoError(t, err)
+
+	for name, token := range map[string]string{
+		"alg none":           sign(jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, purpose),
+		"signed by keyring":  signedWithKeyring,
+		"HS256 other secret": sign(jwt.SigningMethodHS256, []byte("other-secret"), purpose),
+	} {
+		_, err := tokens.ParsePurposeToken(token, tokens.PurposeVerifyEmail)
+		assert.Error(t, err, name)
+	}
+
+	_, err = tokens.ParsePurposeToken(sign(jwt.SigningMethodHS256, issuer.Secret, purpose), tokens.PurposeVerifyEmail)
+	assert.NoError(t, err, "the same claims signed with the secret are valid")
+}
+
+func TestPurposeTokens(t *testing.T) {
+	useTestTokens(t)
+
+	token, err := tokens.GeneratePurposeToken(tokens.PurposeVerifyEmail, testUserID, "someone@example.com", time.Hour)
+	assert.NoError(t, err)
+
+	claims, err := tokens.ParsePurposeToken(token, tokens.PurposeVerifyEmail)
+	require.NoError(t, err)
+	assert.Equal(t, testUserID, claims.Subject)
+	assert.Equal(t, "someone@example.com", claims.Email)
+
+	_, err = tokens.ParsePurposeToken(token, "reset-password")
+	assert.Error(t, err, "tokens only work for their purpose")
+
+	_, err = tokens.ParseAccessToken(token)
+	assert.Error(t, err, "purpose tokens cannot be used as session tokens")
+
+	session, err := tokens.GenerateAccessToken(testUserID, testSessionID, models.RoleUser)
+	assert.NoError(t, err)
+	_, err = tokens.ParsePurposeToken(session, tokens.PurposeVerifyEmail)
+	assert.Error(t, err, "session tokens cannot be used as purpose tokens")
+
+	expired, err := tokens.GeneratePurposeToken(tokens.PurposeVerifyEmail, testUserID, "someone@example.com", -time.Hour)
+	assert.NoError(t, err)
+	_, err = tokens.ParsePurposeToken(expired, tokens.PurposeVerifyEmail)
+	assert.ErrorIs(t, err, tokens.ErrExpired)
+}
+
+func TestTokensNeedInit(t *testing.T) {
+	previous := tokens.Default
+	tokens.Default = nil
+	t.Cleanup(func() { tokens.Default = previous })
+
+	_, err := tokens.GenerateAccessToken(testUserID, testSessionID, models.RoleUser)
+	assert.ErrorIs(t, err, tokens.ErrNotInitialized)
+	_, err = tokens.ParsePurposeToken("token", tokens.PurposeVerifyEmail)
+	assert.ErrorIs(t, err, tokens.ErrNotInitialized)
+}
@@ -0,0 +1,64 @@
+package tokens
+
+import (
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// AccessTokenLifetime is how long an access token is accepted. Clients get a
+// new one from their refresh token once it expires.
+const AccessTokenLifetime = 15 * time.Minute
+
+// AccessClaims carry the user in sub and the session the token was issued
+// for in sid, so logging out of the session revokes the token. The role of
+// the user when the token was issued is in role. The standard jti claim
+// identifies each token.
+type AccessClaims struct {
+	UserID    string `json:"sub"`
+	SessionID string `json:"sid"`
+	Role      string `json:"role,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// GenerateAccessToken signs an access token for the session of userID, who
+// has role, with the keyring of Default.
+func GenerateAccessToken(userID, sessionID, role string) (string, error) {
+	issuer, err := defaultIssuer()
+	if err != nil {
+		return "", err
+	}
+
+	claims := AccessClaims{
+		UserID:           userID,
+		SessionID:        sessionID,
+		Role:             role,
+		RegisteredClaims: issuer.registeredClaims("", issuer.Audience, AccessTokenLifetime),
+	}
+	claims.ID = uuid.NewString()
+
+	return issuer.Keyring.Sign(claims)
+}
+
+// ParseAccessToken verifies tokenString with the keyring of Default and
+// returns its claims. Tokens without a user or a session are refused, since
+// they could not be revoked.
+func ParseAccessToken(tokenString string) (*AccessClaims, error) {
+	issuer, err := defaultIssuer()
+	if err != nil {
+		return nil, err
+	}
+
+	claims := &AccessClaims{}
+	if err := issuer.Keyring.Parse(tokenString, claims, issuer.parserOptions(issuer.Audience)...); err != nil {
+		return nil, err
+	}
+
+	if claims.UserID == "" || claims.SessionID == "" {
+		return nil, errors.New("token without user or session")
+	}
+
+	return claims, nil
+}
@@ -0,0 +1,233 @@
+package tokens
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// minRSABits is the smallest RSA key the keyring accepts.
+const minRSABits = 2048
+
+// Key is a key of the keyring. Private is nil for the keys that only verify
+// tokens signed before a rotation. ID is the RFC 7638 thumbprint of the
+// public key, sent as the kid header of the tokens it signs.
+type Key struct {
+	ID        string
+	Algorithm string
+	Private   crypto.Signer
+	Public    crypto.PublicKey
+}
+
+// NewKey returns the key of public, with private to sign with when not nil.
+// Only RSA keys, used with RS256, and Ed25519 keys, used with EdDSA, are
+// supported.
+func NewKey(private crypto.Signer, public crypto.PublicKey) (*Key, error) {
+	if private != nil {
+		public = private.Public()
+	}
+
+	var algorithm string
+	switch k := public.(type) {
+	case *rsa.PublicKey:
+		if k.N.BitLen() < minRSABits {
+			return nil, fmt.Errorf("RSA keys need at least %d bits", minRSABits)
+		}
+		algorithm = jwt.SigningMethodRS256.Alg()
+	case ed25519.PublicKey:
+		algorithm = jwt.SigningMethodEdDSA.Alg()
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", public)
+	}
+
+	thumbprint, err := (&jose.JSONWebKey{Key: public}).Thumbprint(crypto.SHA256)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Key{
+		ID:        base64.RawURLEncoding.EncodeToString(thumbprint),
+		Algorithm: algorithm,
+		Private:   private,
+		Public:    public,
+	}, nil
+}
+
+// Keyring signs access tokens with one key and verifies them with any of
+// its keys, so that tokens signed with the previous key keep working for a
+// while after a rotation.
+type Keyring struct {
+	signing *Key
+	keys    []*Key
+}
+
+// NewKeyring returns a keyring that signs with signing and also verifies
+// with verification.
+func NewKeyring(signing *Key, verification ...*Key) (*Keyring, error) {
+	if signing == nil || signing.Private == nil {
+		return nil, errors.New("the signing key needs its private key")
+	}
+
+	keyring := &Keyring{signing: signing, keys: []*Key{signing}}
+	for _, key := range verification {
+		if keyring.key(key.ID) == nil {
+			keyring.keys = append(keyring.keys, key)
+		}
+	}
+
+	return keyring, nil
+}
+
+func (k *Keyring) key(id string) *Key {
+	for _, key := range k.keys {
+		if key.ID == id {
+			return key
+		}
+	}
+	return nil
+}
+
+// Sign signs claims with the signing key and names it in the kid header.
+func (k *Keyring) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(k.signing.Algorithm), claims)
+	token.Header["kid"] = k.signing.ID
+	return token.SignedString(k.signing.Private)
+}
+
+// Parse verifies tokenString with the key of its kid header, only accepting
+// the algorithm of that key, and reads its claims into claims.
+func (k *Keyring) Parse(tokenString string, claims jwt.Claims, options ...jwt.ParserOption) error {
+	keyFunc := func(token *jwt.Token) (interface{}, error) {
+		id, _ := token.Header["kid"].(string)
+		key := k.key(id)
+		if key == nil {
+			return nil, errors.New("unknown signing key")
+		}
+		if token.Method.Alg() != key.Algorithm {
+			return nil, errors.New("unexpected signing method")
+		}
+		return key.Public, nil
+	}
+
+	options = append(options, jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}))
+	_, err := jwt.ParseWithClaims(tokenString, claims, keyFunc, options...)
+	return err
+}
+
+// JWKS returns the public keys of the keyring, for other services to
+// verify access tokens with.
+func (k *Keyring) JWKS() jose.JSONWebKeySet {
+	set := jose.JSONWebKeySet{Keys: make([]jose.JSONWebKey, len(k.keys))}
+	for i, key := range k.keys {
+		set.Keys[i] = jose.JSONWebKey{
+			Key:       key.Public,
+			KeyID:     key.ID,
+			Algorithm: key.Algorithm,
+			Use:       "sig",
+		}
+	}
+	return set
+}
+
+// loadKeyring returns a keyring with the private key of the PEM file at
+// JWT_SIGNING_KEY_FILE, and the keys of the comma separated PEM files of
+// JWT_VERIFICATION_KEY_FILES. Without a signing key, it generates one that
+// only lasts until the API restarts.
+func loadKeyring() (*Keyring, error) {
+	var verification []*Key
+	for _, path := range strings.Split(os.Getenv("JWT_VERIFICATION_KEY_FILES"), ",") {
+		if path = strings.TrimSpace(path); path == "" {
+			continue
+		}
+		key, err := ReadKeyFile(path)
+		if err != nil {
+			return nil, err
+		}
+		verification = append(verification, key)
+	}
+
+	var signing *Key
+	var err error
+	if path := os.Getenv("JWT_SIGNING_KEY_FILE"); path != "" {
+		signing, err = ReadKeyFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if signing.Private == nil {
+			return nil, fmt.Errorf("%s is not a private key", path)
+		}
+	} else {
+		fmt.Println("JWT_SIGNING_KEY_FILE is not set. Access tokens are signed with a temporary key.")
+		_, private, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		signing, err = NewKey(private, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return NewKeyring(signing, verification...)
+}
+
+// ReadKeyFile reads the key of a PEM file. Private keys can be PKCS #8 or
+// PKCS #1, and public keys PKIX.
+func ReadKeyFile(path string) (*Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := ParseKeyPEM(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return key, nil
+}
+
+// ParseKeyPEM parses the first key of a PEM document.
+func ParseKeyPEM(data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		private, ok := parsed.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("unsupported key type %T", parsed)
+		}
+		return NewKey(private, nil)
+	case "RSA PRIVATE KEY":
+		private, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return NewKey(private, nil)
+	case "PUBLIC KEY":
+		public, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return NewKey(nil, public)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+}
@@ -0,0 +1,119 @@
+package tokens
+
+import (
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Purposes of the tokens sent to users by email. A token is only accepted for
+// the purpose it was issued for.
+const (
+	PurposeVerifyEmail = "verify-email"
+	// PurposeMFA is the purpose of the token that proves the password was
+	// right when a second factor is still needed to log in.
+	PurposeMFA = "mfa-pending"
+	// PurposeOIDCLogin is the purpose of the token that keeps a login with an
+	// OpenID Connect provider in a cookie until the user comes back from it.
+	PurposeOIDCLogin = "oidc-login"
+)
+
+const (
+	// VerificationTokenLifetime is how long the link to verify an email works.
+	VerificationTokenLifetime = 24 * time.Hour
+	// MFATokenLifetime is how long users have to send their second factor
+	// after their password.
+	MFATokenLifetime = 5 * time.Minute
+	// OIDCLoginLifetime is how long users have to log in with a provider.
+	OIDCLoginLifetime = 10 * time.Minute
+)
+
+// PurposeClaims identify the user in sub and the purpose in aud. Email ties
+// the token to the address it was sent to, so it stops working if the user
+// changes it.
+type PurposeClaims struct {
+	Email string `json:"email"`
+	jwt.RegisteredClaims
+}
+
+// GeneratePurposeToken signs a token that lets userID do purpose for email
+// until lifetime passes.
+func GeneratePurposeToken(purpose, userID, email string, lifetime time.Duration) (string, error) {
+	issuer, err := defaultIssuer()
+	if err != nil {
+		return "", err
+	}
+
+	return issuer.signSecret(PurposeClaims{
+		Email:            email,
+		RegisteredClaims: issuer.registeredClaims(userID, purpose, lifetime),
+	})
+}
+
+// ParsePurposeToken verifies tokenString and that it was issued for purpose,
+// and returns its claims.
+func ParsePurposeToken(tokenString, purpose string) (*PurposeClaims, error) {
+	issuer, err := defaultIssuer()
+	if err != nil {
+		return nil, err
+	}
+
+	claims := &PurposeClaims{}
+	if err := issuer.parseSecret(tokenString, purpose, claims); err != nil {
+		return nil, err
+	}
+
+	if claims.Subject == "" {
+		return nil, errors.New("token without user")
+	}
+
+	return claims, nil
+}
+
+// OIDCLoginClaims carry the values a login with Provider has to be checked
+// against when the user comes back: the state of the redirect, the nonce of
+// the ID token and the PKCE code verifier.
+type OIDCLoginClaims struct {
+	Provider string `json:"provider"`
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	jwt.RegisteredClaims
+}
+
+// GenerateOIDCLoginToken signs the values of a login with provider.
+func GenerateOIDCLoginToken(provider, state, nonce, verifier string) (string, error) {
+	issuer, err := defaultIssuer()
+	if err != nil {
+		return "", err
+	}
+
+	return issuer.signSecret(OIDCLoginClaims{
+		Provider:         provider,
+		State:            state,
+		Nonce:            nonce,
+		Verifier:         verifier,
+		RegisteredClaims: issuer.registeredClaims("", PurposeOIDCLogin, OIDCLoginLifetime),
+	})
+}
+
+// ParseOIDCLoginToken verifies tokenString and that it was issued for a login
+// with provider.
+func ParseOIDCLoginToken(tokenString, provider string) (*OIDCLoginClaims, error) {
+	issuer, err := defaultIssuer()
+	if err != nil {
+		return nil, err
+	}
+
+	claims := &OIDCLoginClaims{}
+	if err := issuer.parseSecret(tokenString, PurposeOIDCLogin, claims); err != nil {
+		return nil, err
+	}
+
+	if claims.Provider != provider || claims.State == "" {
+		return nil, errors.New("token issued for another login")
+	}
+
+	return claims, nil
+}
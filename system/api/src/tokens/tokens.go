@@ -0,0 +1,157 @@
+// Package tokens issues and verifies every JWT of the API: the access tokens
+// of sessions, signed with the keys of a Keyring, and the tokens the API only
+// issues to itself, like the links sent by email, signed with SECRET_JWT.
+//
+// Every token carries the issuer in iss and whom it is for in aud, and is
+// only accepted with the algorithms it can have been signed with, its issuer,
+// its audience, and exp, nbf and iat within the clock skew.
+package tokens
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// DefaultClockSkew is how far apart the clocks of the servers that issue and
+// verify tokens may be by default.
+const DefaultClockSkew = 30 * time.Second
+
+var (
+	// ErrExpired is returned when the token expired, so clients know to
+	// refresh it rather than log in again.
+	ErrExpired = jwt.ErrTokenExpired
+	// ErrNotInitialized is returned when tokens are used before Init.
+	ErrNotInitialized = errors.New("tokens are not initialized")
+)
+
+// Issuer is how tokens are issued and what is accepted when verifying them.
+type Issuer struct {
+	// Name is the iss claim of the tokens, the only one accepted.
+	Name string
+	// Audience is the aud claim of access tokens, the only one accepted. Other
+	// tokens have their purpose as audience.
+	Audience string
+	// ClockSkew is the leeway given to exp, nbf and iat.
+	ClockSkew time.Duration
+	// Keyring signs and verifies access tokens.
+	Keyring *Keyring
+	// Secret signs and verifies the tokens the API issues to itself, with
+	// HS256. Access tokens are never signed with it.
+	Secret []byte
+}
+
+// Default is the issuer of the API, set up by Init.
+var Default *Issuer
+
+// Init sets up Default. The issuer is JWT_ISSUER, which defaults to
+// APP_BASE_URL, and the audience of access tokens JWT_AUDIENCE, which
+// defaults to the issuer. JWT_CLOCK_SKEW is a duration like 30s. The keyring
+// is read from the files of JWT_SIGNING_KEY_FILE and
+// JWT_VERIFICATION_KEY_FILES.
+func Init() error {
+	secret := os.Getenv("SECRET_JWT")
+	if secret == "" {
+		return errors.New("SECRET_JWT is not set")
+	}
+
+	name := os.Getenv("JWT_ISSUER")
+	if name == "" {
+		name = os.Getenv("APP_BASE_URL")
+	}
+	if name == "" {
+		port := os.Getenv("PORT")
+		if port == "" {
+			port = "3000"
+		}
+		name = "http://localhost:" + port
+	}
+	name = strings.TrimSuffix(name, "/")
+
+	audience := os.Getenv("JWT_AUDIENCE")
+	if audience == "" {
+		audience = name
+	}
+
+	clockSkew := DefaultClockSkew
+	if raw := os.Getenv("JWT_CLOCK_SKEW"); raw != "" {
+		d, err := time.ParseDuration(raw)
+		if err != nil || d < 0 || d > 5*time.Minute {
+			return fmt.Errorf("invalid JWT_CLOCK_SKEW: %q", raw)
+		}
+		clockSkew = d
+	}
+
+	keyring, err := loadKeyring()
+	if err != nil {
+		return err
+	}
+
+	Default = &Issuer{
+		Name:      name,
+		Audience:  audience,
+		ClockSkew: clockSkew,
+		Keyring:   keyring,
+		Secret:    []byte(secret),
+	}
+	return nil
+}
+
+func defaultIssuer() (*Issuer, error) {
+	if Default == nil {
+		return nil, ErrNotInitialized
+	}
+	return Default, nil
+}
+
+// registeredClaims returns the claims every token issued now for audience
+// carries, valid until lifetime passes.
+func (i *Issuer) registeredClaims(subject, audience string, lifetime time.Duration) jwt.RegisteredClaims {
+	now := time.Now()
+	return jwt.RegisteredClaims{
+		Issuer:    i.Name,
+		Subject:   subject,
+		Audience:  jwt.ClaimStrings{audience},
+		ExpiresAt: jwt.NewNumericDate(now.Add(lifetime)),
+		NotBefore: jwt.NewNumericDate(now),
+		IssuedAt:  jwt.NewNumericDate(now),
+	}
+}
+
+// parserOptions are the checks every token goes through, for audience.
+func (i *Issuer) parserOptions(audience string) []jwt.ParserOption {
+	return []jwt.ParserOption{
+		jwt.WithIssuer(i.Name),
+		jwt.WithAudience(audience),
+		jwt.WithLeeway(i.ClockSkew),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	}
+}
+
+// signSecret signs claims with Secret.
+func (i *Issuer) signSecret(claims jwt.Claims) (string, error) {
+	if len(i.Secret) == 0 {
+		return "", errors.New("no secret to sign with")
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(i.Secret)
+}
+
+// parseSecret verifies tokenString with Secret, only accepting HS256 so that
+// neither an unsigned token nor an access token gets through, and reads its
+// claims into claims.
+func (i *Issuer) parseSecret(tokenString, audience string, claims jwt.Claims) error {
+	if len(i.Secret) == 0 {
+		return errors.New("no secret to verify with")
+	}
+
+	options := append(i.parserOptions(audience), jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return i.Secret, nil
+	}, options...)
+	return err
+}
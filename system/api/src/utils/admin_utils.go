@@ -0,0 +1,101 @@
+package utils
+
+import (
+	"context"
+
+	"social_api/db"
+	"social_api/models"
+)
+
+// UserAccountCursor is the position of account in lists sorted newest first.
+func UserAccountCursor(account models.UserAccount) Cursor {
+	return Cursor{Time: account.CreatedAt, ID: account.ID}
+}
+
+// ListUsers returns a page of the users, newest first. Only the users with
+// role are listed when it is not empty, and only the suspended ones when
+// suspended is set.
+func ListUsers(role string, suspended bool, page Page) ([]models.UserAccount, *string, error) {
+	pool := db.Pool
+
+	cursorCondition, args := keysetCondition(page.Cursor, "dateofentry", "id", true, []interface{}{page.Limit + 1, role, suspended})
+
+	query := `
+        SELECT id, username, email, email_verified, role, suspended_at, password_reset_required, dateofentry
+        FROM user_profile
+        WHERE ($2::text = '' OR role = $2) AND (NOT $3 OR suspended_at IS NOT NULL) ` + cursorCondition + `
+        ORDER BY dateofentry DESC, id DESC
+        LIMIT $1
+    `
+
+	rows, err := pool.Query(context.Background(), query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	accounts := make([]models.UserAccount, 0)
+	for rows.Next() {
+		var account models.UserAccount
+		err := rows.Scan(&account.ID, &account.Username, &account.Email, &account.EmailVerified, &account.Role, &account.SuspendedAt, &account.PasswordResetRequired, &account.CreatedAt)
+		if err != nil {
+			return nil, nil, err
+		}
+		accounts = append(accounts, account)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	accounts, nextCursor := TrimPage(accounts, page.Limit, UserAccountCursor)
+	return accounts, nextCursor, nil
+}
+
+// SuspendUser keeps userID from logging in and revokes every one of their
+// sessions. Suspending a suspended user keeps the time of the first
+// suspension.
+func SuspendUser(userID string) error {
+	return updateAndRevokeSessions(userID, "UPDATE user_profile SET suspended_at = COALESCE(suspended_at, CURRENT_TIMESTAMP) WHERE id = $1")
+}
+
+// UnsuspendUser lets userID log in again.
+func UnsuspendUser(userID string) error {
+	pool := db.Pool
+
+	query := "UPDATE user_profile SET suspended_at = NULL WHERE id = $1"
+	_, err := pool.Exec(context.Background(), query, userID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// RequirePasswordReset keeps userID from logging in until they reset their
+// password, and revokes every one of their sessions.
+func RequirePasswordReset(userID string) error {
+	return updateAndRevokeSessions(userID, "UPDATE user_profile SET password_reset_required = TRUE WHERE id = $1")
+}
+
+// updateAndRevokeSessions runs query on userID and revokes their sessions in
+// the same transaction.
+func updateAndRevokeSessions(userID, query string) error {
+	pool := db.Pool
+	ctx := context.Background()
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, query, userID); err != nil {
+		return err
+	}
+
+	if _, err := revokeSessions(ctx, tx, "user_id = $1", userID); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
@@ -32,4 +32,72 @@ var (
 	ErrInvalidPasswordType   = errors.New("Error. Invalid password type")
 	ErrInvalidEmailType      = errors.New("Error. Invalid email type")
 	ErrInvalidLoginParams    = errors.New("Error invalid login params")
+	ErrInvalidID             = errors.New("Error invalid ID.")
+	ErrPostNotFound          = errors.New("Error post not found.")
+	ErrFindPost              = errors.New("Error finding post.")
+	ErrSavePost              = errors.New("Error saving post.")
+	ErrUpdatePost            = errors.New("Error updating post.")
+	ErrDeletePost            = errors.New("Error deleting post.")
+	ErrResponseNotFound      = errors.New("Error response not found.")
+	ErrFindResponse          = errors.New("Error finding response.")
+	ErrSaveResponse          = errors.New("Error saving response.")
+	ErrUpdateResponse        = errors.New("Error updating response.")
+	ErrDeleteResponse        = errors.New("Error deleting response.")
+	ErrInvalidParentResponse = errors.New("Error the parent response does not belong to this post.")
+	ErrLike                  = errors.New("Error updating like.")
+	ErrFindLikers            = errors.New("Error finding likes.")
+	ErrInvalidCursor         = errors.New("Error invalid cursor.")
+	ErrFeedStrategy          = errors.New("Error unknown feed strategy.")
+	ErrMissingFile           = errors.New("Error missing file.")
+	ErrFileTooLarge          = errors.New("Error the file is too large.")
+	ErrBodyTooLarge          = errors.New("Error the request body is too large.")
+	ErrLengthRequired        = errors.New("Error the request body needs a Content-Length.")
+	ErrUploadMedia           = errors.New("Error uploading media.")
+	ErrInvalidImage          = errors.New("Error the file is not a valid image.")
+	ErrUpdatePicture         = errors.New("Error updating picture.")
+	ErrUpdateDescription     = errors.New("Error updating description.")
+	ErrFindFollows           = errors.New("Error finding follows.")
+	ErrInvalidRefreshToken   = errors.New("Error invalid refresh token.")
+	ErrRefreshTokenReused    = errors.New("Error refresh token already used. Every session of this login was closed.")
+	ErrRefreshTokenExpired   = errors.New("Error refresh token expired. Please log in again.")
+	ErrRefreshToken          = errors.New("Error refreshing token.")
+	ErrFindSession           = errors.New("Error finding session.")
+	ErrSessionRevoked        = errors.New("Session revoked")
+	ErrSessionNotFound       = errors.New("Error session not found.")
+	ErrFindSessions          = errors.New("Error finding sessions.")
+	ErrRevokeSession         = errors.New("Error revoking session.")
+	ErrInvalidEmailToken     = errors.New("Error invalid or expired email token.")
+	ErrVerifyEmail           = errors.New("Error verifying email.")
+	ErrEmailAlreadyVerified  = errors.New("Error email already verified.")
+	ErrEmailNotVerified      = errors.New("Error you have to verify your email first.")
+	ErrSendEmail             = errors.New("Error sending email.")
+	ErrInvalidResetToken     = errors.New("Error invalid or expired password reset token.")
+	ErrResetPassword         = errors.New("Error resetting password.")
+	ErrWrongPassword         = errors.New("Error current password is wrong.")
+	ErrPasswordReused        = errors.New("Error you used that password recently. Please choose another one.")
+	ErrTOTPAlreadyEnabled    = errors.New("Error two-factor authentication is already enabled.")
+	ErrTOTPNotPending        = errors.New("Error set up two-factor authentication first.")
+	ErrTOTPNotEnabled        = errors.New("Error two-factor authentication is not enabled.")
+	ErrInvalidTOTPCode       = errors.New("Error invalid two-factor authentication code.")
+	ErrInvalidMFAToken       = errors.New("Error invalid or expired login. Please log in again.")
+	ErrTwoFactor             = errors.New("Error updating two-factor authentication.")
+	ErrLogout                = errors.New("Error logging out.")
+	ErrInvalidPasskey        = errors.New("Error invalid or expired passkey response.")
+	ErrPasskeyExists         = errors.New("Error that passkey is already registered.")
+	ErrPasskeyNotFound       = errors.New("Error passkey not found.")
+	ErrPasskey               = errors.New("Error updating passkeys.")
+	ErrUnknownProvider       = errors.New("Error unknown login provider.")
+	ErrInvalidOIDCState      = errors.New("Error invalid or expired login. Please try again.")
+	ErrOIDCLogin             = errors.New("Error logging in with the provider.")
+	ErrOIDCEmailRequired     = errors.New("Error the provider did not share your email.")
+	ErrOIDCAccountExists     = errors.New("Error an account with this email already exists. Log in with your password and verify your email to link it.")
+	ErrInvalidCredentials    = errors.New("Invalid credentials.")
+	ErrTooManyLogins         = errors.New("Error too many failed logins. Please try again later.")
+	ErrTooManyRequests       = errors.New("Error too many requests. Please slow down.")
+	ErrUserSuspended         = errors.New("Error your account is suspended.")
+	ErrPasswordResetRequired = errors.New("Error you have to reset your password. Use the link sent to your email, or ask for a new one.")
+	ErrFindUsers             = errors.New("Error finding users.")
+	ErrInvalidRole           = errors.New("Error invalid role.")
+	ErrRoleTooHigh           = errors.New("Error you cannot do that to a user with this role.")
+	ErrModerateUser          = errors.New("Error updating user.")
 )
@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"context"
+
+	"social_api/db"
+	"social_api/models"
+)
+
+// GetFeed returns the posts of every user followed by userID, newest first,
+// starting after the cursor when there is one.
+//
+// Instead of joining every post of every followed user and sorting the
+// result, the lateral subquery only reads the newest `limit` posts of each
+// followed user from idx_posts_feed, which keeps the query cheap for users
+// that follow thousands of accounts.
+func GetFeed(userID string, cursor *Cursor, limit int) ([]models.Post, error) {
+	pool := db.Pool
+
+	cursorCondition, args := keysetCondition(cursor, "p.created_at", "p.id", true, []interface{}{userID, limit})
+
+	query := `
+        SELECT p.id, p.author_id, p.title, p.description, p.likes, p.images, p.videos, p.created_at, p.updated_at
+        FROM followers f
+        CROSS JOIN LATERAL (
+            SELECT *
+            FROM posts p
+            WHERE p.author_id = f.following_id AND p.deleted_at IS NULL ` + cursorCondition + `
+            ORDER BY p.created_at DESC, p.id DESC
+            LIMIT $2
+        ) p
+        WHERE f.follower_id = $1
+        ORDER BY p.created_at DESC, p.id DESC
+        LIMIT $2
+    `
+
+	rows, err := pool.Query(context.Background(), query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	posts := make([]models.Post, 0)
+	for rows.Next() {
+		post, err := scanPost(rows)
+		if err != nil {
+			return nil, err
+		}
+		posts = append(posts, *post)
+	}
+
+	return posts, rows.Err()
+}
@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v4"
+
+	"social_api/db"
+)
+
+// UseIdentity returns the ID of the user the identity of provider with
+// subject is linked to, and records the login. It returns an empty ID
+// without an error when the identity is not linked yet.
+func UseIdentity(provider, subject string) (string, error) {
+	pool := db.Pool
+
+	query := `
+        UPDATE user_identities SET last_login_at = CURRENT_TIMESTAMP
+        WHERE provider = $1 AND subject = $2
+        RETURNING user_id
+    `
+
+	var userID string
+	err := pool.QueryRow(context.Background(), query, provider, subject).Scan(&userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", nil
+		}
+		return "", err
+	}
+
+	return userID, nil
+}
+
+// LinkIdentity lets userID log in with the identity of provider with
+// subject. email is the one the provider knew them by.
+func LinkIdentity(userID, provider, subject, email string) error {
+	pool := db.Pool
+
+	query := `
+        INSERT INTO user_identities (provider, subject, user_id, email, last_login_at)
+        VALUES ($1, $2, $3, $4, CURRENT_TIMESTAMP)
+    `
+	_, err := pool.Exec(context.Background(), query, provider, subject, userID, email)
+	return err
+}
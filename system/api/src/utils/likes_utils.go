@@ -0,0 +1,99 @@
+package utils
+
+import (
+	"context"
+
+	"social_api/db"
+	"social_api/models"
+)
+
+// LikePost records that the user likes the post. Liking twice is a no-op.
+// It returns the resulting like counter of the post.
+func LikePost(postID, userID string) (int, error) {
+	return setLike("post_likes", "post_id", "posts", postID, userID, true)
+}
+
+// UnlikePost removes the like of the user from the post. Unliking a post that
+// was not liked is a no-op. It returns the resulting like counter of the post.
+func UnlikePost(postID, userID string) (int, error) {
+	return setLike("post_likes", "post_id", "posts", postID, userID, false)
+}
+
+func LikeResponse(responseID, userID string) (int, error) {
+	return setLike("response_likes", "response_id", "responses", responseID, userID, true)
+}
+
+func UnlikeResponse(responseID, userID string) (int, error) {
+	return setLike("response_likes", "response_id", "responses", responseID, userID, false)
+}
+
+// setLike inserts or deletes the row of the join table and moves the counter
+// of the liked row in the same transaction, only when the join table actually
+// changed. The primary key of the join table serializes concurrent likes of
+// the same user, so the counter always matches the number of rows.
+func setLike(likesTable, targetColumn, targetTable, targetID, userID string, like bool) (int, error) {
+	pool := db.Pool
+	ctx := context.Background()
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	var query, counterUpdate string
+	if like {
+		query = "INSERT INTO " + likesTable + " (" + targetColumn + ", user_id) VALUES ($1, $2) ON CONFLICT DO NOTHING"
+		counterUpdate = "likes + 1"
+	} else {
+		query = "DELETE FROM " + likesTable + " WHERE " + targetColumn + " = $1 AND user_id = $2"
+		counterUpdate = "likes - 1"
+	}
+
+	tag, err := tx.Exec(ctx, query, targetID, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	var likes int
+	if tag.RowsAffected() == 1 {
+		query = "UPDATE " + targetTable + " SET likes = " + counterUpdate + " WHERE id = $1 RETURNING likes"
+	} else {
+		query = "SELECT likes FROM " + targetTable + " WHERE id = $1"
+	}
+
+	if err := tx.QueryRow(ctx, query, targetID).Scan(&likes); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+
+	return likes, nil
+}
+
+// GetPostLikers returns a page of the users that liked the post, most recent
+// like first.
+func GetPostLikers(postID string, page Page) ([]models.UserRelevantInfo, *string, error) {
+	return getLikers("post_likes", "post_id", postID, page)
+}
+
+func GetResponseLikers(responseID string, page Page) ([]models.UserRelevantInfo, *string, error) {
+	return getLikers("response_likes", "response_id", responseID, page)
+}
+
+func getLikers(likesTable, targetColumn, targetID string, page Page) ([]models.UserRelevantInfo, *string, error) {
+	cursorCondition, args := keysetCondition(page.Cursor, "l.liked_at", "l.user_id", true, []interface{}{targetID, page.Limit + 1})
+
+	query := `
+        SELECT u.id, u.username, l.liked_at
+        FROM ` + likesTable + ` l
+        JOIN user_profile u ON l.user_id = u.id
+        WHERE l.` + targetColumn + ` = $1 ` + cursorCondition + `
+        ORDER BY l.liked_at DESC, l.user_id DESC
+        LIMIT $2
+    `
+
+	return queryUsersPage(query, args, page.Limit)
+}
@@ -0,0 +1,104 @@
+package utils
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+)
+
+// Cursor points at the last item of a page. Every list is sorted by a
+// timestamp and then by an ID, so the pair is unique. Clients only see it
+// encoded by EncodeCursor.
+type Cursor struct {
+	Time time.Time `json:"t"`
+	ID   string    `json:"id"`
+}
+
+// Page is what a list request asks for: up to Limit items after Cursor, or
+// from the start when Cursor is nil.
+type Page struct {
+	Limit  int
+	Cursor *Cursor
+}
+
+func EncodeCursor(cursor Cursor) string {
+	cursor.Time = cursor.Time.UTC()
+	raw, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func DecodeCursor(encoded string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var cursor Cursor
+	if err := json.Unmarshal(raw, &cursor); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	if _, err := uuid.Parse(cursor.ID); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return &cursor, nil
+}
+
+// ParsePage reads the ?limit= and ?cursor= query parameters. The limit
+// defaults to DefaultPageLimit and is capped at MaxPageLimit.
+func ParsePage(c *fiber.Ctx) (Page, error) {
+	page := Page{Limit: c.QueryInt("limit", DefaultPageLimit)}
+	if page.Limit <= 0 || page.Limit > MaxPageLimit {
+		page.Limit = MaxPageLimit
+	}
+
+	if encoded := c.Query("cursor"); encoded != "" {
+		cursor, err := DecodeCursor(encoded)
+		if err != nil {
+			return Page{}, err
+		}
+		page.Cursor = cursor
+	}
+
+	return page, nil
+}
+
+// TrimPage takes the result of a query that fetched one item more than the
+// limit, which tells whether there is a next page. It drops that extra item
+// and returns the encoded cursor of the next page, or nil on the last one.
+func TrimPage[T any](items []T, limit int, cursorOf func(T) Cursor) ([]T, *string) {
+	if len(items) <= limit {
+		return items, nil
+	}
+
+	items = items[:limit]
+	next := EncodeCursor(cursorOf(items[limit-1]))
+	return items, &next
+}
+
+// keysetCondition returns the SQL condition that keeps the rows after the
+// cursor in a list sorted by timeColumn and idColumn, along with args and the
+// cursor values it refers to. It returns an empty condition without a cursor.
+func keysetCondition(cursor *Cursor, timeColumn, idColumn string, descending bool, args []interface{}) (string, []interface{}) {
+	if cursor == nil {
+		return "", args
+	}
+
+	operator := ">"
+	if descending {
+		operator = "<"
+	}
+
+	args = append(args, cursor.Time, cursor.ID)
+	return fmt.Sprintf("AND (%s, %s) %s ($%d, $%d)", timeColumn, idColumn, operator, len(args)-1, len(args)), args
+}
@@ -0,0 +1,174 @@
+package utils
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/jackc/pgx/v4"
+
+	"social_api/db"
+	"social_api/models"
+)
+
+const passkeyColumns = "id, user_id, name, public_key, attestation_type, transports, aaguid, sign_count, backup_eligible, backup_state, created_at, last_used_at"
+
+func scanPasskey(row pgx.Row) (*models.Passkey, error) {
+	var passkey models.Passkey
+	var transports []string
+	credential := &passkey.Credential
+	err := row.Scan(&credential.ID, &passkey.UserID, &passkey.Name, &credential.PublicKey, &credential.AttestationType, &transports,
+		&credential.Authenticator.AAGUID, &credential.Authenticator.SignCount, &credential.Flags.BackupEligible, &credential.Flags.BackupState,
+		&passkey.CreatedAt, &passkey.LastUsedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, transport := range transports {
+		credential.Transport = append(credential.Transport, protocol.AuthenticatorTransport(transport))
+	}
+	passkey.ID = base64.RawURLEncoding.EncodeToString(credential.ID)
+	passkey.BackedUp = credential.Flags.BackupState
+
+	return &passkey, nil
+}
+
+// SavePasskey stores a credential userID just registered. It returns
+// ErrPasskeyExists when the credential is already registered.
+func SavePasskey(userID, name string, credential webauthn.Credential) (*models.Passkey, error) {
+	pool := db.Pool
+
+	transports := make([]string, len(credential.Transport))
+	for i, transport := range credential.Transport {
+		transports[i] = string(transport)
+	}
+
+	query := `
+        INSERT INTO passkeys (id, user_id, name, public_key, attestation_type, transports, aaguid, sign_count, backup_eligible, backup_state)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+        ON CONFLICT (id) DO NOTHING
+        RETURNING ` + passkeyColumns
+
+	row := pool.QueryRow(context.Background(), query, credential.ID, userID, name, credential.PublicKey, credential.AttestationType, transports,
+		credential.Authenticator.AAGUID, credential.Authenticator.SignCount, credential.Flags.BackupEligible, credential.Flags.BackupState)
+	passkey, err := scanPasskey(row)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrPasskeyExists
+		}
+		return nil, err
+	}
+
+	return passkey, nil
+}
+
+// GetUserPasskeys returns every passkey of userID, oldest first.
+func GetUserPasskeys(userID string) ([]models.Passkey, error) {
+	pool := db.Pool
+
+	query := "SELECT " + passkeyColumns + " FROM passkeys WHERE user_id = $1 ORDER BY created_at, id"
+	rows, err := pool.Query(context.Background(), query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	passkeys := make([]models.Passkey, 0)
+	for rows.Next() {
+		passkey, err := scanPasskey(rows)
+		if err != nil {
+			return nil, err
+		}
+		passkeys = append(passkeys, *passkey)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return passkeys, nil
+}
+
+// UsePasskey records a login with credential and its new signature counter.
+func UsePasskey(credential webauthn.Credential) error {
+	pool := db.Pool
+
+	query := `
+        UPDATE passkeys SET sign_count = $2, backup_state = $3, last_used_at = CURRENT_TIMESTAMP
+        WHERE id = $1
+    `
+	_, err := pool.Exec(context.Background(), query, credential.ID, credential.Authenticator.SignCount, credential.Flags.BackupState)
+	return err
+}
+
+// DeletePasskey removes the passkey of userID with credentialID. It returns
+// ErrPasskeyNotFound when userID has no such passkey.
+func DeletePasskey(userID string, credentialID []byte) error {
+	pool := db.Pool
+
+	tag, err := pool.Exec(context.Background(), "DELETE FROM passkeys WHERE id = $1 AND user_id = $2", credentialID, userID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrPasskeyNotFound
+	}
+
+	return nil
+}
+
+// SaveWebAuthnCeremony keeps session until the authenticator answers its
+// options. userID is empty for logins, where the user is not known yet.
+func SaveWebAuthnCeremony(userID string, session webauthn.SessionData) error {
+	pool := db.Pool
+	ctx := context.Background()
+
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+
+	if _, err := pool.Exec(ctx, "DELETE FROM webauthn_ceremonies WHERE expires_at < $1", time.Now().UTC()); err != nil {
+		return err
+	}
+
+	query := "INSERT INTO webauthn_ceremonies (challenge, user_id, session, expires_at) VALUES ($1, NULLIF($2, '')::uuid, $3, $4)"
+	_, err = pool.Exec(ctx, query, session.Challenge, userID, data, session.Expires.UTC())
+	return err
+}
+
+// TakeWebAuthnCeremony returns the session of the ceremony of userID with
+// challenge and ends it, so that an answer cannot be replayed. It returns nil
+// without an error when there is no such ceremony or it expired.
+func TakeWebAuthnCeremony(challenge, userID string) (*webauthn.SessionData, error) {
+	pool := db.Pool
+
+	query := `
+        DELETE FROM webauthn_ceremonies
+        WHERE challenge = $1 AND user_id IS NOT DISTINCT FROM NULLIF($2, '')::uuid
+        RETURNING session, expires_at
+    `
+
+	var data []byte
+	var expiresAt time.Time
+	err := pool.QueryRow(context.Background(), query, challenge, userID).Scan(&data, &expiresAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if time.Now().UTC().After(expiresAt) {
+		return nil, nil
+	}
+
+	var session webauthn.SessionData
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, err
+	}
+
+	return &session, nil
+}
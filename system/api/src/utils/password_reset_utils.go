@@ -0,0 +1,91 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+
+	"social_api/db"
+)
+
+// CreatePasswordReset stores the hash of a token that lets userID reset their
+// password until expiresAt.
+func CreatePasswordReset(userID, tokenHash string, expiresAt time.Time) error {
+	pool := db.Pool
+
+	query := "INSERT INTO password_resets (user_id, token_hash, expires_at) VALUES ($1, $2, $3)"
+	_, err := pool.Exec(context.Background(), query, userID, tokenHash, expiresAt)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// FindPasswordResetUser returns the user the password reset token with
+// tokenHash belongs to, or ErrInvalidResetToken when it cannot be used.
+func FindPasswordResetUser(tokenHash string) (string, error) {
+	pool := db.Pool
+
+	var userID string
+	query := "SELECT user_id FROM password_resets WHERE token_hash = $1 AND used_at IS NULL AND expires_at > $2"
+	err := pool.QueryRow(context.Background(), query, tokenHash, time.Now().UTC()).Scan(&userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", ErrInvalidResetToken
+		}
+		return "", err
+	}
+
+	return userID, nil
+}
+
+// ResetPassword spends the password reset token with tokenHash and sets the
+// password of its user to passwordHash. Every other reset token of the user
+// is spent too, and every session is revoked. It returns the user, or
+// ErrInvalidResetToken when the token is unknown, spent or expired.
+func ResetPassword(tokenHash, passwordHash string) (string, error) {
+	pool := db.Pool
+	ctx := context.Background()
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback(ctx)
+
+	var userID string
+	query := `
+        SELECT user_id FROM password_resets
+        WHERE token_hash = $1 AND used_at IS NULL AND expires_at > $2
+        FOR UPDATE
+    `
+	err = tx.QueryRow(ctx, query, tokenHash, time.Now().UTC()).Scan(&userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", ErrInvalidResetToken
+		}
+		return "", err
+	}
+
+	query = "UPDATE password_resets SET used_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND used_at IS NULL"
+	if _, err := tx.Exec(ctx, query, userID); err != nil {
+		return "", err
+	}
+
+	if _, err := tx.Exec(ctx, setPasswordQuery, passwordHash, userID); err != nil {
+		return "", err
+	}
+
+	if _, err := revokeSessions(ctx, tx, "user_id = $1", userID); err != nil {
+		return "", err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return "", err
+	}
+
+	return userID, nil
+}
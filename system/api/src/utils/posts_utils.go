@@ -0,0 +1,160 @@
+package utils
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v4"
+
+	"social_api/db"
+	"social_api/models"
+)
+
+const postColumns = "id, author_id, title, description, likes, images, videos, created_at, updated_at"
+
+func scanPost(row pgx.Row) (*models.Post, error) {
+	var post models.Post
+	err := row.Scan(&post.ID, &post.AuthorID, &post.Title, &post.Description, &post.Likes, &post.Images, &post.Videos, &post.CreatedAt, &post.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &post, nil
+}
+
+func SavePost(post models.Post) (*models.Post, error) {
+	pool := db.Pool
+
+	if post.Images == nil {
+		post.Images = []models.Image{}
+	}
+	if post.Videos == nil {
+		post.Videos = []string{}
+	}
+
+	query := `
+        INSERT INTO posts (author_id, title, description, images, videos)
+        VALUES ($1, $2, $3, $4, $5)
+        RETURNING ` + postColumns
+
+	row := pool.QueryRow(context.Background(), query, post.AuthorID, post.Title, post.Description, post.Images, post.Videos)
+	return scanPost(row)
+}
+
+// FindPostById returns nil without an error when the post does not exist or was deleted.
+func FindPostById(postID string) (*models.Post, error) {
+	pool := db.Pool
+
+	query := "SELECT " + postColumns + " FROM posts WHERE id = $1 AND deleted_at IS NULL"
+	post, err := scanPost(pool.QueryRow(context.Background(), query, postID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return post, nil
+}
+
+// PostCursor is the position of post in lists sorted newest first.
+func PostCursor(post models.Post) Cursor {
+	return Cursor{Time: post.CreatedAt, ID: post.ID}
+}
+
+// GetPosts returns a page of posts, newest first. An empty authorID lists
+// every author.
+func GetPosts(authorID string, page Page) ([]models.Post, *string, error) {
+	pool := db.Pool
+
+	cursorCondition, args := keysetCondition(page.Cursor, "created_at", "id", true, []interface{}{authorID, page.Limit + 1})
+
+	query := `
+        SELECT ` + postColumns + `
+        FROM posts
+        WHERE deleted_at IS NULL AND ($1 = '' OR author_id::text = $1) ` + cursorCondition + `
+        ORDER BY created_at DESC, id DESC
+        LIMIT $2
+    `
+
+	rows, err := pool.Query(context.Background(), query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	posts := make([]models.Post, 0)
+	for rows.Next() {
+		post, err := scanPost(rows)
+		if err != nil {
+			return nil, nil, err
+		}
+		posts = append(posts, *post)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	posts, nextCursor := TrimPage(posts, page.Limit, PostCursor)
+	return posts, nextCursor, nil
+}
+
+// GetPostsByIds returns the live posts among postIDs, in the same order as postIDs.
+func GetPostsByIds(postIDs []string) ([]models.Post, error) {
+	pool := db.Pool
+
+	query := "SELECT " + postColumns + " FROM posts WHERE id = ANY($1::uuid[]) AND deleted_at IS NULL"
+
+	rows, err := pool.Query(context.Background(), query, postIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byID := make(map[string]models.Post, len(postIDs))
+	for rows.Next() {
+		post, err := scanPost(rows)
+		if err != nil {
+			return nil, err
+		}
+		byID[post.ID] = *post
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	posts := make([]models.Post, 0, len(byID))
+	for _, postID := range postIDs {
+		if post, ok := byID[postID]; ok {
+			posts = append(posts, post)
+		}
+	}
+
+	return posts, nil
+}
+
+func UpdatePost(post models.Post) (*models.Post, error) {
+	pool := db.Pool
+
+	query := `
+        UPDATE posts
+        SET title = $1, description = $2, images = $3, videos = $4, updated_at = CURRENT_TIMESTAMP
+        WHERE id = $5 AND deleted_at IS NULL
+        RETURNING ` + postColumns
+
+	row := pool.QueryRow(context.Background(), query, post.Title, post.Description, post.Images, post.Videos, post.ID)
+	return scanPost(row)
+}
+
+// DeletePost soft deletes the post so it disappears from every listing.
+func DeletePost(postID string) error {
+	pool := db.Pool
+
+	query := "UPDATE posts SET deleted_at = CURRENT_TIMESTAMP WHERE id = $1 AND deleted_at IS NULL"
+	_, err := pool.Exec(context.Background(), query, postID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
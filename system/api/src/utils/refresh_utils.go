@@ -0,0 +1,116 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+
+	"social_api/db"
+)
+
+// CreateRefreshToken stores the hash of the first refresh token of a session,
+// whose family lasts until familyExpiresAt.
+func CreateRefreshToken(userID, sessionID, tokenHash string, expiresAt, familyExpiresAt time.Time) error {
+	pool := db.Pool
+
+	query := `
+        INSERT INTO refresh_tokens (family_id, user_id, token_hash, expires_at, family_expires_at)
+        VALUES ($1, $2, $3, $4, $5)
+    `
+
+	_, err := pool.Exec(context.Background(), query, sessionID, userID, tokenHash, expiresAt, familyExpiresAt)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// RotateRefreshToken spends the refresh token with tokenHash and stores
+// newTokenHash in its family in its place. It returns the user and the session
+// the family belongs to, and the role of the user, read in the same
+// transaction so that a token is never spent without it.
+//
+// A refresh token can only be spent once. Presenting a spent one means that
+// it leaked, so the whole family is revoked and neither the thief nor the
+// legitimate client can refresh again, and the session is revoked with it.
+func RotateRefreshToken(tokenHash, newTokenHash string, lifetime time.Duration) (string, string, string, error) {
+	pool := db.Pool
+	ctx := context.Background()
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return "", "", "", err
+	}
+	defer tx.Rollback(ctx)
+
+	var id, familyID, userID, role string
+	var expiresAt, familyExpiresAt time.Time
+	var usedAt, revokedAt *time.Time
+
+	query := `
+        SELECT rt.id, rt.family_id, rt.user_id, up.role, rt.expires_at, rt.family_expires_at, rt.used_at, rt.revoked_at
+        FROM refresh_tokens rt
+        JOIN user_profile up ON up.id = rt.user_id
+        WHERE rt.token_hash = $1
+        FOR UPDATE OF rt
+    `
+	err = tx.QueryRow(ctx, query, tokenHash).Scan(&id, &familyID, &userID, &role, &expiresAt, &familyExpiresAt, &usedAt, &revokedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", "", "", ErrInvalidRefreshToken
+		}
+		return "", "", "", err
+	}
+
+	if revokedAt != nil {
+		return "", "", "", ErrInvalidRefreshToken
+	}
+
+	if usedAt != nil {
+		query = "UPDATE refresh_tokens SET revoked_at = CURRENT_TIMESTAMP WHERE family_id = $1 AND revoked_at IS NULL"
+		if _, err := tx.Exec(ctx, query, familyID); err != nil {
+			return "", "", "", err
+		}
+		query = "UPDATE sessions SET revoked_at = CURRENT_TIMESTAMP WHERE id = $1 AND revoked_at IS NULL"
+		if _, err := tx.Exec(ctx, query, familyID); err != nil {
+			return "", "", "", err
+		}
+		if err := tx.Commit(ctx); err != nil {
+			return "", "", "", err
+		}
+		return "", "", "", ErrRefreshTokenReused
+	}
+
+	now := time.Now().UTC()
+	if now.After(expiresAt) || now.After(familyExpiresAt) {
+		return "", "", "", ErrRefreshTokenExpired
+	}
+
+	query = "UPDATE refresh_tokens SET used_at = CURRENT_TIMESTAMP WHERE id = $1"
+	if _, err := tx.Exec(ctx, query, id); err != nil {
+		return "", "", "", err
+	}
+
+	// The new token never outlives its family.
+	newExpiresAt := now.Add(lifetime)
+	if newExpiresAt.After(familyExpiresAt) {
+		newExpiresAt = familyExpiresAt
+	}
+
+	query = `
+        INSERT INTO refresh_tokens (family_id, user_id, token_hash, expires_at, family_expires_at)
+        VALUES ($1, $2, $3, $4, $5)
+    `
+	if _, err := tx.Exec(ctx, query, familyID, userID, newTokenHash, newExpiresAt, familyExpiresAt); err != nil {
+		return "", "", "", err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return "", "", "", err
+	}
+
+	return userID, familyID, role, nil
+}
@@ -0,0 +1,305 @@
+package utils
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v4"
+
+	"social_api/db"
+	"social_api/models"
+)
+
+const responseColumns = "id, post_id, parent_id, author_id, content, likes, images, videos, deleted_at IS NOT NULL, created_at, updated_at"
+
+// MaxThreadReplies is how many of the replies below a top level response are
+// nested in it when listing threads. The others are only counted.
+const MaxThreadReplies = 100
+
+// scanResponse scans the responseColumns of row, followed by the extra
+// columns into extra.
+func scanResponse(row pgx.Row, extra ...interface{}) (*models.Response, error) {
+	var response models.Response
+	dest := []interface{}{&response.ID, &response.PostID, &response.ParentID, &response.AuthorID, &response.Content, &response.Likes, &response.Images, &response.Videos, &response.Deleted, &response.CreatedAt, &response.UpdatedAt}
+	err := row.Scan(append(dest, extra...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &response, nil
+}
+
+func SaveResponse(response models.Response) (*models.Response, error) {
+	pool := db.Pool
+
+	if response.Images == nil {
+		response.Images = []models.Image{}
+	}
+	if response.Videos == nil {
+		response.Videos = []string{}
+	}
+
+	query := `
+        INSERT INTO responses (post_id, parent_id, author_id, content, images, videos)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        RETURNING ` + responseColumns
+
+	row := pool.QueryRow(context.Background(), query, response.PostID, response.ParentID, response.AuthorID, response.Content, response.Images, response.Videos)
+	return scanResponse(row)
+}
+
+// FindResponseById returns nil without an error when the response does not
+// exist or was deleted, or when the post it answers was deleted.
+func FindResponseById(responseID string) (*models.Response, error) {
+	pool := db.Pool
+
+	query := `
+        SELECT ` + responseColumns + `
+        FROM responses r
+        WHERE r.id = $1 AND r.deleted_at IS NULL
+            AND EXISTS (SELECT 1 FROM posts p WHERE p.id = r.post_id AND p.deleted_at IS NULL)
+    `
+	response, err := scanResponse(pool.QueryRow(context.Background(), query, responseID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// ResponseCursor is the position of response in lists sorted oldest first.
+func ResponseCursor(response *models.Response) Cursor {
+	return Cursor{Time: response.CreatedAt, ID: response.ID}
+}
+
+// hasLiveReplyCondition keeps the responses r that are not deleted, or that
+// still have a reply below them that is not deleted, like
+// pruneDeletedResponses.
+const hasLiveReplyCondition = `(r.deleted_at IS NULL OR EXISTS (
+            WITH RECURSIVE below AS (
+                SELECT id, deleted_at FROM responses WHERE parent_id = r.id
+                UNION ALL
+                SELECT reply.id, reply.deleted_at FROM responses reply JOIN below ON reply.parent_id = below.id
+            )
+            SELECT 1 FROM below WHERE deleted_at IS NULL
+        ))`
+
+// GetPostResponses returns a page of the responses of a post, oldest first,
+// as a flat list. Deleted responses that have live replies are kept as empty
+// placeholders so their replies still have a parent.
+func GetPostResponses(postID string, page Page) ([]*models.Response, *string, error) {
+	cursorCondition, args := keysetCondition(page.Cursor, "r.created_at", "r.id", false, []interface{}{postID, page.Limit + 1})
+
+	query := `
+        SELECT ` + responseColumns + `
+        FROM responses r
+        WHERE r.post_id = $1 ` + cursorCondition + `
+            AND ` + hasLiveReplyCondition + `
+        ORDER BY r.created_at ASC, r.id ASC
+        LIMIT $2
+    `
+
+	responses, err := queryResponses(query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	responses, nextCursor := TrimPage(responses, page.Limit, ResponseCursor)
+	return responses, nextCursor, nil
+}
+
+// GetPostResponseThreads returns a page of the top level responses of a post,
+// oldest first, each with the first MaxThreadReplies replies below it nested
+// in Replies. The number of the other ones is in MoreReplies.
+func GetPostResponseThreads(postID string, page Page) ([]*models.Response, *string, error) {
+	cursorCondition, args := keysetCondition(page.Cursor, "r.created_at", "r.id", false, []interface{}{postID, page.Limit + 1})
+
+	query := `
+        SELECT ` + responseColumns + `
+        FROM responses r
+        WHERE r.post_id = $1 AND r.parent_id IS NULL ` + cursorCondition + `
+        ORDER BY r.created_at ASC, r.id ASC
+        LIMIT $2
+    `
+
+	roots, err := queryResponses(query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	roots, nextCursor := TrimPage(roots, page.Limit, ResponseCursor)
+	if len(roots) == 0 {
+		return roots, nextCursor, nil
+	}
+
+	rootIDs := make([]string, 0, len(roots))
+	byID := make(map[string]*models.Response, len(roots))
+	for _, root := range roots {
+		rootIDs = append(rootIDs, root.ID)
+		byID[root.ID] = root
+	}
+
+	query = `
+        WITH RECURSIVE thread AS (
+            SELECT responses.*, parent_id AS root_id FROM responses WHERE parent_id = ANY($1::uuid[])
+            UNION ALL
+            SELECT reply.*, thread.root_id FROM responses reply JOIN thread ON reply.parent_id = thread.id
+        ), numbered AS (
+            SELECT thread.*,
+                ROW_NUMBER() OVER (PARTITION BY root_id ORDER BY created_at ASC, id ASC) AS position,
+                COUNT(*) OVER (PARTITION BY root_id) AS total
+            FROM thread
+        )
+        SELECT ` + responseColumns + `, root_id, total
+        FROM numbered r
+        WHERE position <= $2
+        ORDER BY r.created_at ASC, r.id ASC
+    `
+
+	rows, err := db.Pool.Query(context.Background(), query, rootIDs, MaxThreadReplies)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	thread := append([]*models.Response{}, roots...)
+	counted := make(map[string]bool, len(roots))
+	for rows.Next() {
+		var rootID string
+		var total int
+		reply, err := scanResponse(rows, &rootID, &total)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		root := byID[rootID]
+		if !counted[rootID] {
+			root.MoreReplies = total
+			counted[rootID] = true
+		}
+
+		// A reply whose parent did not fit is left out with it.
+		if _, ok := byID[*reply.ParentID]; !ok {
+			continue
+		}
+		root.MoreReplies--
+
+		blankDeleted(reply)
+		byID[reply.ID] = reply
+		thread = append(thread, reply)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	return BuildResponseTree(pruneDeletedResponses(thread)), nextCursor, nil
+}
+
+// queryResponses runs a query that selects responseColumns from the
+// responses aliased as r, blanking the deleted ones.
+func queryResponses(query string, args ...interface{}) ([]*models.Response, error) {
+	pool := db.Pool
+
+	rows, err := pool.Query(context.Background(), query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	responses := make([]*models.Response, 0)
+	for rows.Next() {
+		response, err := scanResponse(rows)
+		if err != nil {
+			return nil, err
+		}
+		blankDeleted(response)
+		responses = append(responses, response)
+	}
+
+	return responses, rows.Err()
+}
+
+// blankDeleted empties the content of response when it was deleted.
+func blankDeleted(response *models.Response) {
+	if response.Deleted {
+		response.Content = ""
+		response.Images = []models.Image{}
+		response.Videos = []string{}
+	}
+}
+
+// pruneDeletedResponses drops the deleted responses that no longer have any
+// live reply below them. Top level responses whose thread did not fit are
+// kept, since their other replies may be live.
+func pruneDeletedResponses(responses []*models.Response) []*models.Response {
+	hasLiveReply := make(map[string]bool)
+
+	// Replies always come after their parent, so walking backwards sees every
+	// reply before the response it answers.
+	for i := len(responses) - 1; i >= 0; i-- {
+		response := responses[i]
+		if response.ParentID != nil && (!response.Deleted || hasLiveReply[response.ID]) {
+			hasLiveReply[*response.ParentID] = true
+		}
+	}
+
+	kept := make([]*models.Response, 0, len(responses))
+	for _, response := range responses {
+		if !response.Deleted || hasLiveReply[response.ID] || response.MoreReplies > 0 {
+			kept = append(kept, response)
+		}
+	}
+
+	return kept
+}
+
+// BuildResponseTree nests a flat list of responses under their parents and
+// returns the top level responses.
+func BuildResponseTree(responses []*models.Response) []*models.Response {
+	byID := make(map[string]*models.Response, len(responses))
+	for _, response := range responses {
+		byID[response.ID] = response
+	}
+
+	roots := make([]*models.Response, 0)
+	for _, response := range responses {
+		if response.ParentID != nil {
+			if parent, ok := byID[*response.ParentID]; ok {
+				parent.Replies = append(parent.Replies, response)
+				continue
+			}
+		}
+		roots = append(roots, response)
+	}
+
+	return roots
+}
+
+func UpdateResponse(response models.Response) (*models.Response, error) {
+	pool := db.Pool
+
+	query := `
+        UPDATE responses
+        SET content = $1, images = $2, videos = $3, updated_at = CURRENT_TIMESTAMP
+        WHERE id = $4 AND deleted_at IS NULL
+        RETURNING ` + responseColumns
+
+	row := pool.QueryRow(context.Background(), query, response.Content, response.Images, response.Videos, response.ID)
+	return scanResponse(row)
+}
+
+// DeleteResponse soft deletes the response so the replies below it keep their thread.
+func DeleteResponse(responseID string) error {
+	pool := db.Pool
+
+	query := "UPDATE responses SET deleted_at = CURRENT_TIMESTAMP WHERE id = $1 AND deleted_at IS NULL"
+	_, err := pool.Exec(context.Background(), query, responseID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
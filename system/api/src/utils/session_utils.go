@@ -0,0 +1,158 @@
+package utils
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+
+	"social_api/db"
+	"social_api/models"
+)
+
+// The times of a session come from two clocks, which only agree when the
+// database runs in UTC: expires_at is set by the API in UTC, while the others
+// are set by the database. Each one is only compared with times of its own
+// clock.
+const sessionColumns = "id, user_id, user_agent, ip, request_id, created_at, last_seen_at, expires_at"
+
+// sessionSeenInterval is how stale last_seen_at gets before a request
+// refreshes it, so that not every request writes to the session.
+const sessionSeenInterval = time.Minute
+
+// CreateSession starts a session and returns its ID. Every token issued for
+// the login carries it, and its refresh tokens use it as family.
+func CreateSession(session models.Session) (string, error) {
+	pool := db.Pool
+
+	query := `
+        INSERT INTO sessions (user_id, user_agent, ip, request_id, expires_at)
+        VALUES ($1, $2, $3, $4, $5)
+        RETURNING id
+    `
+
+	var sessionID string
+	err := pool.QueryRow(context.Background(), query, session.UserID, session.UserAgent, session.IP, session.RequestID, session.ExpiresAt).Scan(&sessionID)
+	if err != nil {
+		return "", err
+	}
+
+	return sessionID, nil
+}
+
+// TouchSession reports whether the session is still active and records that
+// it was just seen.
+func TouchSession(sessionID string) (bool, error) {
+	pool := db.Pool
+
+	query := `
+        WITH seen AS (
+            UPDATE sessions SET last_seen_at = CURRENT_TIMESTAMP
+            WHERE id = $1 AND revoked_at IS NULL AND last_seen_at < CURRENT_TIMESTAMP - make_interval(secs => $2)
+        )
+        SELECT EXISTS (
+            SELECT 1 FROM sessions
+            WHERE id = $1 AND revoked_at IS NULL AND expires_at > $3
+        )
+    `
+
+	var active bool
+	err := pool.QueryRow(context.Background(), query, sessionID, sessionSeenInterval.Seconds(), time.Now().UTC()).Scan(&active)
+	if err != nil {
+		return false, err
+	}
+
+	return active, nil
+}
+
+// SessionCursor is the position of session in lists sorted newest first.
+func SessionCursor(session models.Session) Cursor {
+	return Cursor{Time: session.CreatedAt, ID: session.ID}
+}
+
+// GetUserSessions returns a page of the active sessions of userID, newest first.
+func GetUserSessions(userID string, page Page) ([]models.Session, *string, error) {
+	pool := db.Pool
+
+	cursorCondition, args := keysetCondition(page.Cursor, "created_at", "id", true, []interface{}{userID, page.Limit + 1, time.Now().UTC()})
+
+	query := `
+        SELECT ` + sessionColumns + `
+        FROM sessions
+        WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > $3 ` + cursorCondition + `
+        ORDER BY created_at DESC, id DESC
+        LIMIT $2
+    `
+
+	rows, err := pool.Query(context.Background(), query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	sessions := make([]models.Session, 0)
+	for rows.Next() {
+		var session models.Session
+		err := rows.Scan(&session.ID, &session.UserID, &session.UserAgent, &session.IP, &session.RequestID, &session.CreatedAt, &session.LastSeenAt, &session.ExpiresAt)
+		if err != nil {
+			return nil, nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	sessions, nextCursor := TrimPage(sessions, page.Limit, SessionCursor)
+	return sessions, nextCursor, nil
+}
+
+// RevokeSession revokes the session of userID and its refresh tokens, so none
+// of the tokens issued for it are accepted anymore. It returns
+// ErrSessionNotFound when userID has no such active session.
+func RevokeSession(userID, sessionID string) error {
+	revoked, err := revokeSessions(context.Background(), db.Pool, "id = $1 AND user_id = $2", sessionID, userID)
+	if err != nil {
+		return err
+	}
+	if revoked == 0 {
+		return ErrSessionNotFound
+	}
+
+	return nil
+}
+
+// RevokeUserSessions revokes every session of userID.
+func RevokeUserSessions(userID string) error {
+	_, err := revokeSessions(context.Background(), db.Pool, "user_id = $1", userID)
+	return err
+}
+
+// querier is what revokeSessions needs from a pool or a transaction.
+type querier interface {
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+// revokeSessions revokes the sessions that match condition along with their
+// refresh tokens, and returns how many were revoked.
+func revokeSessions(ctx context.Context, q querier, condition string, args ...interface{}) (int, error) {
+	query := `
+        WITH revoked AS (
+            UPDATE sessions SET revoked_at = CURRENT_TIMESTAMP
+            WHERE ` + condition + ` AND revoked_at IS NULL
+            RETURNING id
+        ), revoked_tokens AS (
+            UPDATE refresh_tokens SET revoked_at = CURRENT_TIMESTAMP
+            WHERE family_id IN (SELECT id FROM revoked) AND revoked_at IS NULL
+        )
+        SELECT COUNT(*) FROM revoked
+    `
+
+	var revoked int
+	err := q.QueryRow(ctx, query, args...).Scan(&revoked)
+	if err != nil {
+		return 0, err
+	}
+
+	return revoked, nil
+}
@@ -0,0 +1,144 @@
+package utils
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v4"
+
+	"social_api/db"
+	"social_api/models"
+)
+
+// FindTOTP returns nil without an error when userID never set up two-factor
+// authentication.
+func FindTOTP(userID string) (*models.TOTP, error) {
+	pool := db.Pool
+
+	var totp models.TOTP
+	query := "SELECT user_id, secret, confirmed_at IS NOT NULL, last_used_step FROM user_totp WHERE user_id = $1"
+	err := pool.QueryRow(context.Background(), query, userID).Scan(&totp.UserID, &totp.Secret, &totp.Confirmed, &totp.LastUsedStep)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &totp, nil
+}
+
+// SaveTOTPSecret starts setting up two-factor authentication for userID with
+// secret, replacing a previous setup that was never confirmed. It returns
+// ErrTOTPAlreadyEnabled when it is already on.
+func SaveTOTPSecret(userID, secret string) error {
+	pool := db.Pool
+
+	query := `
+        INSERT INTO user_totp (user_id, secret) VALUES ($1, $2)
+        ON CONFLICT (user_id) DO UPDATE SET secret = EXCLUDED.secret, created_at = CURRENT_TIMESTAMP
+        WHERE user_totp.confirmed_at IS NULL
+    `
+	tag, err := pool.Exec(context.Background(), query, userID, secret)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrTOTPAlreadyEnabled
+	}
+
+	return nil
+}
+
+// ConfirmTOTP turns two-factor authentication on for userID, spending the
+// step of the code that confirmed it, and replaces their recovery codes with
+// codeHashes.
+func ConfirmTOTP(userID string, step int64, codeHashes []string) error {
+	pool := db.Pool
+	ctx := context.Background()
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+        UPDATE user_totp SET confirmed_at = CURRENT_TIMESTAMP, last_used_step = $2
+        WHERE user_id = $1 AND confirmed_at IS NULL
+    `
+	tag, err := tx.Exec(ctx, query, userID, step)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrTOTPNotPending
+	}
+
+	if _, err := tx.Exec(ctx, "DELETE FROM recovery_codes WHERE user_id = $1", userID); err != nil {
+		return err
+	}
+
+	for _, codeHash := range codeHashes {
+		query = "INSERT INTO recovery_codes (user_id, code_hash) VALUES ($1, $2)"
+		if _, err := tx.Exec(ctx, query, userID, codeHash); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// UseTOTPStep spends the time step of a TOTP code of userID. It returns false
+// when that step or a later one was already used, so a code cannot be
+// replayed.
+func UseTOTPStep(userID string, step int64) (bool, error) {
+	pool := db.Pool
+
+	query := `
+        UPDATE user_totp SET last_used_step = $2
+        WHERE user_id = $1 AND confirmed_at IS NOT NULL AND (last_used_step IS NULL OR last_used_step < $2)
+    `
+	tag, err := pool.Exec(context.Background(), query, userID, step)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+// UseRecoveryCode spends the recovery code of userID with codeHash. It
+// returns false when there is no such code or it was already used.
+func UseRecoveryCode(userID, codeHash string) (bool, error) {
+	pool := db.Pool
+
+	query := "UPDATE recovery_codes SET used_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL"
+	tag, err := pool.Exec(context.Background(), query, userID, codeHash)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+// DisableTOTP turns two-factor authentication off for userID and drops their
+// recovery codes.
+func DisableTOTP(userID string) error {
+	pool := db.Pool
+	ctx := context.Background()
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "DELETE FROM recovery_codes WHERE user_id = $1", userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, "DELETE FROM user_totp WHERE user_id = $1", userID); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
@@ -7,7 +7,6 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
-	"os"
 	"reflect"
 	"strings"
 	"sync"
@@ -15,33 +14,18 @@ import (
 
 	"social_api/schemas"
 
-	"github.com/dgrijalva/jwt-go"
 	"github.com/gofiber/fiber/v2"
 	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
+	"golang.org/x/crypto/bcrypt"
 
 	"social_api/db"
-	"social_api/libs"
 	"social_api/models"
 )
 
 var pool *pgxpool.Pool
 var poolOnce sync.Once
 
-type Claims struct {
-	UserID string `json:"sub"`
-	jwt.StandardClaims
-}
-
-var ErrTokenExpired = errors.New("Token has expired")
-
-func (c Claims) Valid() error {
-	if !c.VerifyExpiresAt(time.Now().Unix(), true) {
-		return ErrTokenExpired
-	}
-	return nil
-}
-
 func IsUserTaken(email, username string) bool {
 	query := "SELECT COUNT(*) FROM user_profile WHERE email = $1 OR username = $2"
 	var count int
@@ -107,9 +91,29 @@ func FindUserByEmailOrUsername(email, username string) (*models.User, error) {
 	pool := db.Pool
 
 	var user models.User
-	query := "SELECT Id, Username, Firstname, Lastname, Email, Password, Picture FROM user_profile WHERE Email = $1 OR Username = $2"
+	query := "SELECT Id, Username, Firstname, Lastname, Email, Email_verified, Password, Picture, Description, Role, Suspended_at, Password_reset_required FROM user_profile WHERE Email = $1 OR Username = $2"
 	row := pool.QueryRow(context.Background(), query, email, username)
-	err := row.Scan(&user.ID, &user.Username, &user.FirstName, &user.LastName, &user.Email, &user.Password, &user.Picture)
+	err := row.Scan(&user.ID, &user.Username, &user.FirstName, &user.LastName, &user.Email, &user.EmailVerified, &user.Password, &user.Picture, &user.Description, &user.Role, &user.SuspendedAt, &user.PasswordResetRequired)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// FindUserByEmail returns the user with email, whatever its case, or nil
+// when there is none. An account with that exact email comes first.
+func FindUserByEmail(email string) (*models.User, error) {
+	pool := db.Pool
+
+	var user models.User
+	query := "SELECT Id, Username, Firstname, Lastname, Email, Email_verified, Password, Picture, Description, Role, Suspended_at, Password_reset_required FROM user_profile WHERE LOWER(Email) = LOWER($1) ORDER BY Email = $1 DESC LIMIT 1"
+	row := pool.QueryRow(context.Background(), query, email)
+	err := row.Scan(&user.ID, &user.Username, &user.FirstName, &user.LastName, &user.Email, &user.EmailVerified, &user.Password, &user.Picture, &user.Description, &user.Role, &user.SuspendedAt, &user.PasswordResetRequired)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, nil
@@ -125,12 +129,12 @@ func FindUserById(userID string) (*models.User, error) {
 	pool := db.Pool
 
 	var user models.User
-	query := "SELECT ID, Username, FirstName, LastName, Email, Password, Picture, Description FROM user_profile WHERE ID = $1"
+	query := "SELECT ID, Username, FirstName, LastName, Email, Email_verified, Password, Picture, Description, Role, Suspended_at, Password_reset_required FROM user_profile WHERE ID = $1"
 
 	var picture, description sql.NullString
 
 	row := pool.QueryRow(context.Background(), query, userID)
-	err := row.Scan(&user.ID, &user.Username, &user.FirstName, &user.LastName, &user.Email, &user.Password, &user.Picture, &user.Description)
+	err := row.Scan(&user.ID, &user.Username, &user.FirstName, &user.LastName, &user.Email, &user.EmailVerified, &user.Password, &user.Picture, &user.Description, &user.Role, &user.SuspendedAt, &user.PasswordResetRequired)
 
 	if err != nil {
 		return nil, err
@@ -174,37 +178,52 @@ func ValidateRequiredFields(c *fiber.Ctx, requestBody interface{}, requiredField
 
 func UserWithoutPassword(user models.User, ID string) map[string]interface{} {
 	return map[string]interface{}{
-		"ID":        ID,
-		"username":  user.Username,
-		"email":     user.Email,
-		"firstname": user.FirstName,
-		"lastname":  user.LastName,
-		"picture":   user.Picture,
+		"ID":            ID,
+		"username":      user.Username,
+		"email":         user.Email,
+		"emailVerified": user.EmailVerified,
+		"firstname":     user.FirstName,
+		"lastname":      user.LastName,
+		"picture":       user.Picture,
+		"description":   user.Description,
+		"role":          user.Role,
 	}
 }
 
 func UserWithoutPasswordAndEmail(user models.User, ID string) map[string]interface{} {
 	return map[string]interface{}{
-		"ID":       ID,
-		"username": user.Username,
-		"picture":  user.Picture,
+		"ID":          ID,
+		"username":    user.Username,
+		"picture":     user.Picture,
+		"description": user.Description,
 	}
 }
 
-func ExtractUserIDFromToken(tokenString string) (string, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &libs.CustomClaims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(os.Getenv("SECRET_JWT")), nil
-	})
+// VerifyEmail marks the email of userID as verified, as long as it is still
+// email. It returns false when the user no longer has that email.
+func VerifyEmail(userID, email string) (bool, error) {
+	pool := db.Pool
+
+	query := "UPDATE user_profile SET email_verified = TRUE WHERE id = $1 AND email = $2"
+	tag, err := pool.Exec(context.Background(), query, userID, email)
 	if err != nil {
-		return "", err
+		return false, err
 	}
 
-	userID := token.Claims.(*libs.CustomClaims).UserID
-	if userID == "" {
-		return "", errors.New("UserID is empty")
+	return tag.RowsAffected() == 1, nil
+}
+
+func IsEmailVerified(userID string) (bool, error) {
+	pool := db.Pool
+
+	var verified bool
+	query := "SELECT email_verified FROM user_profile WHERE id = $1"
+	err := pool.QueryRow(context.Background(), query, userID).Scan(&verified)
+	if err != nil {
+		return false, err
 	}
 
-	return userID, nil
+	return verified, nil
 }
 
 func UpdateUsername(userID string, newUsername string) error {
@@ -219,11 +238,20 @@ func UpdateUsername(userID string, newUsername string) error {
 	return nil
 }
 
+// setPasswordQuery replaces the password of a user and keeps the previous one
+// in their history. A reset forced by an admin is done once it runs.
+const setPasswordQuery = `
+    WITH previous AS (
+        INSERT INTO password_history (user_id, password_hash)
+        SELECT id, password FROM user_profile WHERE id = $2
+    )
+    UPDATE user_profile SET password = $1, password_reset_required = FALSE WHERE id = $2
+`
+
 func UpdatePassword(userID string, newPassword string) error {
 	pool := db.Pool
 
-	query := "UPDATE user_profile SET password = $1 WHERE id = $2"
-	_, err := pool.Exec(context.Background(), query, newPassword, userID)
+	_, err := pool.Exec(context.Background(), setPasswordQuery, newPassword, userID)
 	if err != nil {
 		fmt.Println("Error updating password:", err)
 		return err
@@ -232,6 +260,73 @@ func UpdatePassword(userID string, newPassword string) error {
 	return nil
 }
 
+// IsPasswordReused reports whether password is the current password of userID
+// or one of the ones before it, up to historySize passwords in total.
+func IsPasswordReused(userID, password string, historySize int) (bool, error) {
+	if historySize <= 0 {
+		return false, nil
+	}
+
+	pool := db.Pool
+
+	query := `
+        (SELECT password FROM user_profile WHERE id = $1)
+        UNION ALL
+        (SELECT password_hash FROM password_history WHERE user_id = $1 ORDER BY changed_at DESC LIMIT $2)
+    `
+
+	rows, err := pool.Query(context.Background(), query, userID, historySize-1)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	hashes := make([]string, 0, historySize)
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			return false, err
+		}
+		hashes = append(hashes, hash)
+	}
+	if err := rows.Err(); err != nil {
+		return false, err
+	}
+
+	for _, hash := range hashes {
+		if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func UpdatePicture(userID string, pictureURL string) error {
+	pool := db.Pool
+
+	query := "UPDATE user_profile SET picture = $1 WHERE id = $2"
+	_, err := pool.Exec(context.Background(), query, pictureURL, userID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UpdateDescription stores the description, or clears it when it is empty.
+func UpdateDescription(userID string, description string) error {
+	pool := db.Pool
+
+	query := "UPDATE user_profile SET description = NULLIF($1, '') WHERE id = $2"
+	_, err := pool.Exec(context.Background(), query, description, userID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
 func HasExtraFields(requestBody schemas.LoginRequest, allowedFields []string) bool {
 	fieldCount := 0
 
@@ -306,57 +401,90 @@ func IsNotFollowing(followerID string, followedID string) (bool, error) {
 	return count == 0, nil
 }
 
-func ParseToken(tokenString string) (string, error) {
-	var jwtKey = os.Getenv("SECRET_JWT")
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(jwtKey), nil
-	})
-
-	if err != nil {
-		if err == jwt.ErrSignatureInvalid {
-			return "", errors.New("signature is invalid")
-		}
-		return "", errors.New("could not parse token")
-	}
-
-	claims, ok := token.Claims.(*Claims)
-	if !ok || !token.Valid {
-		return "", errors.New("invalid token")
-	}
+// GetFollowers returns a page of the users that follow uuid, most recent
+// follow first.
+func GetFollowers(uuid string, page Page) ([]models.UserRelevantInfo, *string, error) {
+	return getFollows("f.following_id", "f.follower_id", uuid, page)
+}
 
-	return claims.UserID, nil
+// GetFollowing returns a page of the users that uuid follows, most recent
+// follow first.
+func GetFollowing(uuid string, page Page) ([]models.UserRelevantInfo, *string, error) {
+	return getFollows("f.follower_id", "f.following_id", uuid, page)
 }
 
-func GetFollowers(uuid string) ([]models.UserRelevantInfo, error) {
-	pool := db.Pool
+// getFollows lists the users in listedColumn of the follows where
+// filterColumn is uuid.
+func getFollows(filterColumn, listedColumn, uuid string, page Page) ([]models.UserRelevantInfo, *string, error) {
+	cursorCondition, args := keysetCondition(page.Cursor, "f.followed_at", listedColumn, true, []interface{}{uuid, page.Limit + 1})
 
-	// Consulta para obtener solo los ID y username de los seguidores
 	query := `
-        SELECT u.id, u.username
+        SELECT u.id, u.username, f.followed_at
         FROM followers f
-        JOIN user_profile u ON f.follower_id = u.id
-        WHERE f.following_id = $1
+        JOIN user_profile u ON ` + listedColumn + ` = u.id
+        WHERE ` + filterColumn + ` = $1 ` + cursorCondition + `
+        ORDER BY f.followed_at DESC, ` + listedColumn + ` DESC
+        LIMIT $2
     `
 
-	rows, err := pool.Query(context.Background(), query, uuid)
+	return queryUsersPage(query, args, page.Limit)
+}
+
+// queryUsersPage runs a query that selects the ID and the username of users
+// along with the time the list is sorted by, and trims it into a page.
+func queryUsersPage(query string, args []interface{}, limit int) ([]models.UserRelevantInfo, *string, error) {
+	pool := db.Pool
+
+	rows, err := pool.Query(context.Background(), query, args...)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer rows.Close()
 
-	var followers []models.UserRelevantInfo
+	type listedUser struct {
+		user     models.UserRelevantInfo
+		listedAt time.Time
+	}
+
+	listed := make([]listedUser, 0)
 	for rows.Next() {
-		var user models.UserRelevantInfo
-		err := rows.Scan(&user.ID, &user.Username)
-		if err != nil {
-			return nil, err
+		var item listedUser
+		if err := rows.Scan(&item.user.ID, &item.user.Username, &item.listedAt); err != nil {
+			return nil, nil, err
 		}
-		followers = append(followers, user)
+		listed = append(listed, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
 	}
 
-	if len(followers) == 0 {
-		return nil, errors.New("No followers found")
+	listed, nextCursor := TrimPage(listed, limit, func(item listedUser) Cursor {
+		return Cursor{Time: item.listedAt, ID: item.user.ID}
+	})
+
+	users := make([]models.UserRelevantInfo, 0, len(listed))
+	for _, item := range listed {
+		users = append(users, item.user)
+	}
+
+	return users, nextCursor, nil
+}
+
+// CountFollows returns how many users follow userID and how many users userID follows.
+func CountFollows(userID string) (int, int, error) {
+	pool := db.Pool
+
+	query := `
+        SELECT
+            (SELECT COUNT(*) FROM followers WHERE following_id = $1),
+            (SELECT COUNT(*) FROM followers WHERE follower_id = $1)
+    `
+
+	var followers, following int
+	err := pool.QueryRow(context.Background(), query, userID).Scan(&followers, &following)
+	if err != nil {
+		return 0, 0, err
 	}
 
-	return followers, nil
+	return followers, following, nil
 }